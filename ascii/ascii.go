@@ -194,7 +194,7 @@ func ImageToASCII(url string) (string, error) {
 
 	str := canvas.String()
 	str = strings.Replace(str, "`", "'", -1)
-	fmt.Printf(str)
+	fmt.Print(str)
 
 	return "```" + str + "```", err
 }
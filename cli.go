@@ -0,0 +1,233 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/tadej/hinko/model"
+)
+
+// CLICommand - signature of offline subcommands run as `hinko <command> [args]`; returns the exit code.
+// db is the opened database, or nil for migrate-store, which opens its stores itself
+type CLICommand func(*model.DB, []string) int
+
+// CLICommands - subcommands that work on the database without connecting to Slack
+var CLICommands = map[string]CLICommand{
+	"export":        cliExport,
+	"import":        cliImport,
+	"migrate":       cliMigrate,
+	"migrate-store": cliMigrateStore,
+	"restore":       cliRestore,
+}
+
+// runCLI runs an offline subcommand with the database at DATABASE_PATH
+func runCLI(args []string) int {
+	fn := CLICommands[args[0]]
+	if fn == nil {
+		fmt.Fprintln(os.Stderr, "Unknown command "+args[0])
+		fmt.Fprintln(os.Stderr, "Usage: hinko [export | import [--replace] dump.json | migrate [--dry-run] | "+
+			"migrate-store --from leveldb --to bolt target | restore snapshot]")
+		return 2
+	}
+
+	// migrate-store opens both stores itself
+	if args[0] == "migrate-store" {
+		return fn(nil, args[1:])
+	}
+
+	var db *model.DB
+	var err error
+	if args[0] == "migrate" {
+		// migrate runs the migrations itself so it can do a dry run
+		db, err = model.OpenStore(os.Getenv("DATABASE_DRIVER"), os.Getenv("DATABASE_PATH"))
+	} else {
+		db, err = model.OpenDatabase(os.Getenv("DATABASE_DRIVER"), os.Getenv("DATABASE_PATH"))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Opening database: "+err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	return fn(db, args[1:])
+}
+
+// cliExport writes a JSON dump of the database to stdout: hinko export > dump.json
+func cliExport(db *model.DB, args []string) int {
+	dump, err := db.ExportDatabase()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Export failed: "+err.Error())
+		return 1
+	}
+
+	js, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Export failed: "+err.Error())
+		return 1
+	}
+
+	fmt.Println(string(js))
+	return 0
+}
+
+// cliImport reads a JSON dump and merges it into the database, or replaces it: hinko import [--replace] dump.json
+func cliImport(db *model.DB, args []string) int {
+	replace := false
+	if len(args) > 0 && args[0] == "--replace" {
+		replace = true
+		args = args[1:]
+	}
+
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: hinko import [--replace] dump.json")
+		return 2
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Import failed: "+err.Error())
+		return 1
+	}
+
+	var dump model.Dump
+	err = json.Unmarshal(data, &dump)
+	if err == nil {
+		err = db.ImportDatabase(dump, replace)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Import failed: "+err.Error())
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "Import finished.")
+	return 0
+}
+
+// cliMigrate upgrades the database to the latest schema version, or reports what would change: hinko migrate [--dry-run]
+func cliMigrate(db *model.DB, args []string) int {
+	dryRun := len(args) > 0 && args[0] == "--dry-run"
+
+	fmt.Printf("Schema version %d, latest %d\n", db.GetSchemaVersion(), model.LatestSchemaVersion())
+
+	reports, err := db.Migrate(dryRun)
+	for _, r := range reports {
+		if dryRun {
+			fmt.Println("Would apply migration " + r.String())
+		} else {
+			fmt.Println("Applied migration " + r.String())
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Migration failed: "+err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// cliMigrateStore copies the database at DATABASE_PATH into an empty store with another driver:
+// hinko migrate-store --from leveldb --to bolt target
+func cliMigrateStore(db *model.DB, args []string) int {
+	from := os.Getenv("DATABASE_DRIVER")
+	if from == "" {
+		from = model.DriverLevelDB
+	}
+	to := ""
+	target := ""
+
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--from" && i+1 < len(args):
+			i++
+			from = args[i]
+		case args[i] == "--to" && i+1 < len(args):
+			i++
+			to = args[i]
+		default:
+			target = args[i]
+		}
+	}
+
+	if to == "" || target == "" {
+		fmt.Fprintln(os.Stderr, "Usage: hinko migrate-store --from leveldb --to bolt target")
+		return 2
+	}
+
+	src, err := model.NewStore(from, os.Getenv("DATABASE_PATH"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Opening source database: "+err.Error())
+		return 1
+	}
+	defer src.Close()
+
+	dst, err := model.NewStore(to, target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Opening target database: "+err.Error())
+		return 1
+	}
+	defer dst.Close()
+
+	empty := true
+	dst.Iterate("", func(key string, value string) bool {
+		empty = false
+		return false
+	})
+	if !empty {
+		fmt.Fprintln(os.Stderr, "Target database "+target+" isn't empty")
+		return 1
+	}
+
+	count, err := model.CopyStore(src, dst)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Copying failed: "+err.Error())
+		return 1
+	}
+
+	fmt.Printf("Copied %d keys from %s to %s. Set DATABASE_DRIVER=%s and DATABASE_PATH=%s to use it.\n", count, from, to, to, target)
+	return 0
+}
+
+// cliRestore replaces the database with a snapshot from BACKUP_PATH or a file: hinko restore hinko-20261018T120000.000000000.json
+func cliRestore(db *model.DB, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: hinko restore snapshot")
+		return 2
+	}
+
+	path, err := model.FindSnapshot(args[0])
+	if err == nil {
+		err = db.RestoreSnapshot(path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Restore failed: "+err.Error())
+		return 1
+	}
+
+	fmt.Fprintln(os.Stderr, "Restored "+path)
+	return 0
+}
@@ -0,0 +1,144 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// MaxAuditResults limits how many audit records are listed
+var MaxAuditResults = 20
+
+// Audited wraps a mutating command so every call is written to the audit log with its outcome
+func Audited(fn ProcessCommand) ProcessCommand {
+	return func(parts []string, msg slack.MessageInfo) string {
+		startRecording(msg)
+		ret := fn(parts, msg)
+		reactions := stopRecording(msg)
+
+		result := "ok"
+		for _, r := range reactions {
+			if isFailureReaction(r) {
+				result = "failed (" + r + ")"
+				break
+			}
+		}
+
+		err := db.AddAuditRecord(model.AuditRecord{UserID: msg.UserID, Channel: msg.Channel,
+			Command: strings.Join(parts, " "), Result: result})
+		if err != nil {
+			reactions = append(reactions, EmojiCommandError)
+		}
+
+		for _, r := range reactions {
+			React(msg, r)
+		}
+
+		return ret
+	}
+}
+
+// auditWrite writes a key-value change made outside an Audited command, such as a pipeline redirection
+// or a plugin's KV changes, to the audit log
+func auditWrite(msg slack.MessageInfo, command string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "failed (" + EmojiCommandError + ")"
+	}
+
+	err = db.AddAuditRecord(model.AuditRecord{UserID: msg.UserID, Channel: msg.Channel, Command: command, Result: result})
+	if err != nil {
+		React(msg, EmojiCommandError)
+	}
+}
+
+// ProcessCommandAudit lists audit records: audit score TEAM1:TEAM2, audit @user, audit since 2026-10-01
+func ProcessCommandAudit(parts []string, msg slack.MessageInfo) string {
+	var since time.Time
+	var filter func(model.AuditRecord) bool
+
+	switch {
+	case len(parts) < 2:
+	case len(parts) >= 3 && strings.ToLower(parts[1]) == "score":
+		team1, team2, err := teamsNamesFromString(parts[2])
+		if err != nil {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+		filter = func(r model.AuditRecord) bool {
+			fields := strings.Fields(r.Command)
+			if len(fields) < 3 || strings.ToLower(fields[0]) != "score" {
+				return false
+			}
+			t1, t2, err := teamsNamesFromString(fields[2])
+			return err == nil && (t1 == team1 && t2 == team2 || t1 == team2 && t2 == team1)
+		}
+	case len(parts) >= 3 && strings.ToLower(parts[1]) == "since":
+		var err error
+		since, err = time.ParseInLocation("2006-01-02", parts[2], time.Local)
+		if err != nil {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+	default:
+		userID, ok := model.MentionID(parts[1], model.UserMentionPrefix)
+		if !ok {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+		filter = func(r model.AuditRecord) bool {
+			return r.UserID == userID
+		}
+	}
+
+	records, err := db.GetAuditRecords(since, filter)
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+
+	if len(records) == 0 {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
+	lang := Language(msg)
+
+	ret := i18n.T(lang, "audit.title")
+	if len(records) > MaxAuditResults {
+		ret = i18n.T(lang, "audit.latest", MaxAuditResults, len(records))
+		records = records[len(records)-MaxAuditResults:]
+	}
+
+	for _, r := range records {
+		ret += "\n" + i18n.T(lang, "audit.entry", r.Timestamp.Local().Format("2006-01-02 15:04"),
+			"<@"+r.UserID+">", "<#"+r.Channel+">", r.Command, r.Result)
+	}
+
+	return ret
+}
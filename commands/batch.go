@@ -0,0 +1,212 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/slack"
+)
+
+// StopOnErrorOption makes a batch stop at the first failing line
+var StopOnErrorOption = "--stop-on-error"
+
+var (
+	recordersMutex sync.Mutex
+	recorders      = make(map[string][]*[]string)
+)
+
+func messageKey(msg slack.MessageInfo) string {
+	return msg.Channel + "/" + msg.Timestamp
+}
+
+// startRecording makes React collect reactions for msg instead of sending them to Slack. Recordings can be nested
+func startRecording(msg slack.MessageInfo) {
+	recordersMutex.Lock()
+	defer recordersMutex.Unlock()
+
+	key := messageKey(msg)
+	recorders[key] = append(recorders[key], &[]string{})
+}
+
+// stopRecording ends the innermost recording for msg and returns the reactions it collected
+func stopRecording(msg slack.MessageInfo) []string {
+	recordersMutex.Lock()
+	defer recordersMutex.Unlock()
+
+	key := messageKey(msg)
+	stack := recorders[key]
+	if len(stack) == 0 {
+		return nil
+	}
+
+	reactions := stack[len(stack)-1]
+	if len(stack) == 1 {
+		delete(recorders, key)
+	} else {
+		recorders[key] = stack[:len(stack)-1]
+	}
+
+	return *reactions
+}
+
+// recordReaction stores the reaction in the innermost recording for msg and reports whether there was one
+func recordReaction(msg slack.MessageInfo, reaction string) bool {
+	recordersMutex.Lock()
+	defer recordersMutex.Unlock()
+
+	stack := recorders[messageKey(msg)]
+	if len(stack) == 0 {
+		return false
+	}
+
+	reactions := stack[len(stack)-1]
+	*reactions = append(*reactions, reaction)
+	return true
+}
+
+func isFailureReaction(reaction string) bool {
+	return reaction == EmojiCommandNotFound || reaction == EmojiParametersWrong ||
+		reaction == EmojiCommandError || reaction == EmojiCommandWarning
+}
+
+// CommandSeparatorEscape is a literal ";" inside a command: put motto work\; play
+var CommandSeparatorEscape = `\;`
+
+var slackEntity = regexp.MustCompile(`^&(amp|lt|gt|quot|#[0-9]+);`)
+
+// entityLength returns the length of the HTML entity Slack escaped at the start of s (&amp;, &lt;, &gt;, &#39;), or 0
+func entityLength(s string) int {
+	return len(slackEntity.FindString(s))
+}
+
+// splitLines splits a message into commands on newlines and ";", leaving Slack links and mentions (<...>)
+// and escaped characters (&amp;) intact. An escaped "\;" is kept in the command as a plain ";"
+func splitLines(message string) []string {
+	var lines []string
+	var line strings.Builder
+	depth := 0
+
+	for i := 0; i < len(message); i++ {
+		switch {
+		case strings.HasPrefix(message[i:], CommandSeparatorEscape):
+			line.WriteByte(';')
+			i += len(CommandSeparatorEscape) - 1
+			continue
+		case entityLength(message[i:]) > 0:
+			n := entityLength(message[i:])
+			line.WriteString(message[i : i+n])
+			i += n - 1
+			continue
+		case message[i] == '<':
+			depth++
+		case message[i] == '>' && depth > 0:
+			depth--
+		case (message[i] == '\n' || message[i] == ';') && depth == 0:
+			lines = append(lines, line.String())
+			line.Reset()
+			continue
+		}
+		line.WriteByte(message[i])
+	}
+	lines = append(lines, line.String())
+
+	var ret []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			ret = append(ret, line)
+		}
+	}
+
+	return ret
+}
+
+// stopOnErrorFlag removes StopOnErrorOption from the start of message if it is the whole first word,
+// along with the whitespace after it, and reports whether it was there
+func stopOnErrorFlag(message string) (string, bool) {
+	rest := strings.TrimLeftFunc(message, unicode.IsSpace)
+	if !strings.HasPrefix(rest, StopOnErrorOption) {
+		return message, false
+	}
+
+	rest = rest[len(StopOnErrorOption):]
+	if rest != "" && !unicode.IsSpace(rune(rest[0])) {
+		return message, false
+	}
+
+	return strings.TrimLeftFunc(rest, unicode.IsSpace), true
+}
+
+// Execute runs every command in a message and returns the response text.
+// A message with several commands (separated by newlines or ";") gets a per-line summary instead of reactions
+func Execute(message string, msg slack.MessageInfo) string {
+	message, stopOnError := stopOnErrorFlag(message)
+
+	lines := splitLines(message)
+
+	if len(lines) == 0 {
+		React(msg, EmojiCommandNotFound)
+		return ""
+	}
+
+	if len(lines) == 1 {
+		return executePipeline(lines[0], msg)
+	}
+
+	ret := ""
+	succeeded := 0
+
+	for i, line := range lines {
+		startRecording(msg)
+		text := executePipeline(line, msg)
+		reactions := stopRecording(msg)
+
+		status := EmojiCommandOK
+		for _, r := range reactions {
+			if isFailureReaction(r) {
+				status = r
+				break
+			}
+		}
+
+		ret += "\n" + strconv.Itoa(i+1) + ". :" + status + ": `" + line + "`"
+		if text != "" {
+			ret += "\n" + text
+		}
+
+		if status == EmojiCommandOK {
+			succeeded++
+		} else if stopOnError {
+			ret += "\n" + i18n.N(Language(msg), "batch.stopped", len(lines)-i-1, len(lines)-i-1)
+			break
+		}
+	}
+
+	return i18n.N(Language(msg), "batch.summary", len(lines), succeeded, len(lines)) + ret
+}
@@ -24,47 +24,88 @@
 package commands
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tadej/hinko/ascii"
+	"github.com/tadej/hinko/i18n"
 	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/plugins"
 	"github.com/tadej/hinko/slack"
 )
 
+// db is the database the commands read and write, set with UseDatabase
+var db *model.DB
+
+// UseDatabase makes the commands read and write d
+func UseDatabase(d *model.DB) {
+	db = d
+}
+
 // ProcessCommand - function signature — all command processing functions adhere to this format
 type ProcessCommand func([]string, slack.MessageInfo) string
 
 // AcceptedCommands - accepted text commands with their corresponding processor functions
 var AcceptedCommands = map[string]ProcessCommand{
-	"put":         ProcessCommandPut,
+	"put":         Audited(ProcessCommandPut),
 	"get":         ProcessCommandGet,
+	"del":         Audited(ProcessCommandDel),
+	"exists":      ProcessCommandExists,
+	"keys":        ProcessCommandKeys,
+	"history":     ProcessCommandHistory,
+	"rollback":    Audited(ProcessCommandRollback),
+	"incr":        ProcessCommandIncr,
+	"decr":        ProcessCommandDecr,
+	"karma":       ProcessCommandKarma,
+	"export":      ProcessCommandExport,
+	"backup":      ProcessCommandBackup,
 	"shark":       ProcessCommandShark,
 	"animate":     ProcessCommandAnimate,
 	"help":        ProcessCommandHelp,
 	"randompairs": ProcessCommandRandomPairs,
 	"randomteams": ProcessCommandRandomTeams,
 	"group":       ProcessCommandGroup,
+	"groups":      ProcessCommandGroups,
 	"ascii":       ProcessCommandASCII,
 	"score":       ProcessCommandScore,
+	"plugins":     ProcessCommandPlugins,
+	"audit":       ProcessCommandAudit,
+	"language":    ProcessCommandLanguage,
+	"pairs":       ProcessCommandPairs,
+	"rating":      ProcessCommandRating,
+	"settings":    ProcessCommandSettings,
+	"verify":      ProcessCommandVerify,
 }
 
 // AcceptedGroupSubCommands - accepted text group subcommands with their corresponding processor functions
 var AcceptedGroupSubCommands = map[string]ProcessCommand{
-	"set":    ProcessCommandGroupSet,
-	"create": ProcessCommandGroupSet,
-	"add":    ProcessCommandGroupAdd,
-	"remove": ProcessCommandGroupRemove,
-	"list":   ProcessCommandGroupList,
+	"set":      Audited(ProcessCommandGroupSet),
+	"create":   Audited(ProcessCommandGroupSet),
+	"add":      Audited(ProcessCommandGroupAdd),
+	"remove":   Audited(ProcessCommandGroupRemove),
+	"list":     ProcessCommandGroupList,
+	"info":     ProcessCommandGroupInfo,
+	"delete":   Audited(ProcessCommandGroupDelete),
+	"rename":   Audited(ProcessCommandGroupRename),
+	"copy":     Audited(ProcessCommandGroupCopy),
+	"describe": Audited(ProcessCommandGroupDescribe),
+	"pause":    Audited(ProcessCommandGroupPause),
+	"resume":   Audited(ProcessCommandGroupResume),
+	"link":     Audited(ProcessCommandGroupLink),
+	"unlink":   Audited(ProcessCommandGroupUnlink),
+	"sync":     ProcessCommandGroupSync,
 }
 
 // AcceptedScoreSubCommands - accepted score subcommands with their corresponding processor functions
 var AcceptedScoreSubCommands = map[string]ProcessCommand{
-	"set":   ProcessCommandScoreSet,
-	"add":   ProcessCommandScoreSet,
+	"set":   Audited(ProcessCommandScoreSet),
+	"add":   Audited(ProcessCommandScoreSet),
 	"get":   ProcessCommandScoreGet,
-	"reset": ProcessCommandScoreReset,
+	"reset": Audited(ProcessCommandScoreReset),
 }
 
 // EmojiCommandNotFound ‍‍🤷‍♀️
@@ -88,35 +129,148 @@ var TeamNamesGroup = "teamnames"
 // PairNamesGroup DB key contains a list of space-delimited pair names
 var PairNamesGroup = "pairnames"
 
+// AdminUsers - IDs of the users allowed to run admin commands such as export
+var AdminUsers []string
+
+// IsAdmin reports whether the sender of msg is an admin
+func IsAdmin(msg slack.MessageInfo) bool {
+	for _, id := range AdminUsers {
+		if id == msg.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCommand returns the processor for a command name, falling back to external plugins
+func GetCommand(name string) ProcessCommand {
+	name = strings.ToLower(name)
+
+	fn := AcceptedCommands[name]
+	if fn != nil {
+		return fn
+	}
+
+	if plugins.Find(name) != nil {
+		return ProcessCommandPlugin
+	}
+
+	return nil
+}
+
+// HelpCommands - command syntax listed by the help command
+var HelpCommands = []string{
+	"`help`",
+	"`language`, `language sl`, `language channel sl`",
+	"`settings`",
+	"`settings set group|teamsize|matchup|animations value`",
+	"`settings unset name`",
+	"`put key value`, `put #here key value`, `put me key value`",
+	"`put --ttl 2h key value`",
+	"`put key --variant text with {user} {channel} {date} {random:group}`",
+	"`get key`, `get #here key`, `get me key`, `get key@3`",
+	"`history key`, `rollback key 2`",
+	"`incr key [n]`, `decr key [n]`",
+	"`@user++`, `thing--`, `karma top`, `karma @user`",
+	"`del key`, `exists key`",
+	"`keys [prefix] [page]`, `keys #here`, `keys me`",
+	"`group groupname list`",
+	"`group groupname create @user1 @user2 @user3 ...`",
+	"`group groupname add @user1 @user2 @user3 ...`",
+	"`group groupname remove @user1 @user2 @user3 ...`",
+	"`group groupname add %othergroup`",
+	"`group groupname create --allow-non-users name1 name2 ...`",
+	"`groups`",
+	"`group groupname info`",
+	"`group groupname describe \"text\"`",
+	"`group groupname rename newname`",
+	"`group groupname copy newname`",
+	"`group groupname delete`",
+	"`group groupname pause @user1 ... [until 2026-10-28]`",
+	"`group groupname resume @user1 ...`",
+	"`verify draw-id`",
+	"`group groupname link @slack-user-group [--push] [--replace]`",
+	"`group groupname sync`",
+	"`group groupname unlink`",
+	"`score add team1:team2 score1:score2`",
+	"`score add team1:team2 score1:score2 *` (will return current score)",
+	"`score add @user1+@user2:@user3+@user4 score1:score2`",
+	"`score get team2:team1`",
+	"`score reset team2:team1`",
+	"`score reset team2:team1 score2:score1`",
+	"`randompairs @user1 @user2 @user3 ...`",
+	"`randompairs group`",
+	"`randomteams teamsize @user1 @user2 @user3 ...`",
+	"`randomteams teamsize group`",
+	"`randompairs group1+group2-group3`",
+	"`randomteams teamsize group --balanced`",
+	"`rating @user [value|reset]`",
+	"`randompairs --fresh group`",
+	"`randompairs --rotate group`",
+	"`pairs history group`",
+	"`group groupname list | randompairs`",
+	"`randomteams teamsize group > key`",
+	"`[--stop-on-error] command1; command2 ...`",
+	"`ascii https://imageurl`",
+	"`shark`",
+	"`animate`",
+	"`plugins`",
+	"`audit score team1:team2`, `audit @user`, `audit since 2026-10-01`",
+	"`export`, `backup now`, `backup list`",
+}
+
+// Language returns the language to respond to msg in
+func Language(msg slack.MessageInfo) string {
+	return db.GetLanguage(msg.UserID, msg.Channel)
+}
+
 // ProcessCommandHelp returns a help message
 func ProcessCommandHelp(parts []string, msg slack.MessageInfo) string {
-	infoMessage :=
-		`Try the following commands:` + "\n" +
-			"`help`\n" +
-			"`put key value`\n" +
-			"`get key`\n" +
-			"`group groupname list`\n" +
-			"`group groupname create @user1 @user2 @user3 ...`\n" +
-			"`group groupname add @user1 @user2 @user3 ...`\n" +
-			"`group groupname remove @user1 @user2 @user3 ...`\n" +
-			"`score add team1:team2 score1:score2`\n" +
-			"`score add team1:team2 score1:score2 * (will return current score)`\n" +
-			"`score get team2:team1`\n" +
-			"`score reset team2:team1`\n" +
-			"`score reset team2:team1 score2:score1`\n" +
-			"`randompairs @user1 @user2 @user3 ...`\n" +
-			"`randompairs group`\n" +
-			"`randomteams teamsize @user1 @user2 @user3 ...`\n" +
-			"`randomteams teamsize group`\n" +
-			"`ascii https://imageurl`\n" +
-			"`shark`\n" +
-			"`animate`\n\n" +
-			" reserved groups: _pairnames_, _teamnames_\n\n" +
-			"More info:\nhttps://github.com/tadej/hinko"
+	lang := Language(msg)
+
+	infoMessage := i18n.T(lang, "help.intro") + "\n" +
+		strings.Join(HelpCommands, "\n") + "\n\n" +
+		" " + i18n.T(lang, "help.reserved") + "\n\n" +
+		i18n.T(lang, "help.more") + "\nhttps://github.com/tadej/hinko"
 
 	return infoMessage
 }
 
+// ProcessCommandLanguage shows or sets the language preference: language, language sl, language channel sl
+func ProcessCommandLanguage(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 2 {
+		lang := Language(msg)
+		channelLang := db.GetChannelLanguage(msg.Channel)
+		if !i18n.Supported(channelLang) {
+			channelLang = i18n.DefaultLanguage
+		}
+		return i18n.T(lang, "language.current", i18n.Name(lang), i18n.Name(channelLang),
+			"`"+strings.Join(i18n.Languages(), "` `")+"`")
+	}
+
+	var err error
+	lang := strings.ToLower(parts[len(parts)-1])
+
+	if !i18n.Supported(lang) {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	if len(parts) >= 3 && strings.ToLower(parts[1]) == "channel" {
+		err = db.SetChannelLanguage(msg.Channel, lang)
+	} else {
+		err = db.SetUserLanguage(msg.UserID, lang)
+	}
+
+	if err != nil {
+		React(msg, EmojiCommandError)
+	} else {
+		React(msg, EmojiCommandOK)
+	}
+
+	return ""
+}
+
 // ProcessCommandASCII converts an image to ASCII
 func ProcessCommandASCII(parts []string, msg slack.MessageInfo) string {
 	ret, err := ascii.ImageToASCII(parts[1])
@@ -129,6 +283,11 @@ func ProcessCommandASCII(parts []string, msg slack.MessageInfo) string {
 
 // ProcessCommandShark animates an ASCII shark
 func ProcessCommandShark(parts []string, msg slack.MessageInfo) string {
+	if db.GetChannelSettings(msg.Channel).AnimationsDisabled {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
 	// sending functon bodies as parameter so ascii doesn't have to know "slack"
 	go ascii.DoSharkAnimation(30, 2, 300,
 		func(txt string) (string, string) {
@@ -143,6 +302,11 @@ func ProcessCommandShark(parts []string, msg slack.MessageInfo) string {
 
 // ProcessCommandAnimate animates a pendulum in ASCII
 func ProcessCommandAnimate(parts []string, msg slack.MessageInfo) string {
+	if db.GetChannelSettings(msg.Channel).AnimationsDisabled {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
 	// sending functon bodies as parameter so ascii doesn't have to know "slack"
 	go ascii.DoFrameAnimation(30, 300,
 		func(txt string) (string, string) {
@@ -157,29 +321,45 @@ func ProcessCommandAnimate(parts []string, msg slack.MessageInfo) string {
 
 // ProcessCommandGroupList lists members of a group
 func ProcessCommandGroupList(parts []string, msg slack.MessageInfo) string {
-	group, err := model.GetGroup(parts[1])
+	return GroupList(parts, msg).Text
+}
+
+// GroupList lists members of a group and returns the active ones as structured output, with included groups expanded
+func GroupList(parts []string, msg slack.MessageInfo) Output {
+	group, err := db.GetGroupInfo(parts[1])
 	if err != nil {
 		React(msg, EmojiCommandWarning)
-		return ""
+		return Output{}
+	}
+
+	members, err := db.ResolveGroup(parts[1])
+	if err != nil {
+		React(msg, EmojiCommandWarning)
+		return Output{}
 	}
-	return "`" + parts[1] + "` members: " + strings.Join(group, " ")
+
+	lang := Language(msg)
+	return Output{Text: i18n.T(lang, "group.members", parts[1], strings.Join(group.Members, " ")) + formatPaused(group, lang),
+		Members: members}
 }
 
 // ProcessCommandGroupSet creates a new group
 func ProcessCommandGroupSet(parts []string, msg slack.MessageInfo) string {
-	ProcessGroupCommandError(model.SetGroup(parts[1], parts[3:]), msg, true)
-	return ""
+	return changeGroupMembers(parts, msg, db.SetGroup)
 }
 
 // ProcessCommandGroupAdd adds members to a group
 func ProcessCommandGroupAdd(parts []string, msg slack.MessageInfo) string {
-	ProcessGroupCommandError(model.AddToGroup(parts[1], parts[3:]), msg, true)
-	return ""
+	return changeGroupMembers(parts, msg, db.AddToGroup)
 }
 
 // ProcessCommandGroupRemove removes members from a group
 func ProcessCommandGroupRemove(parts []string, msg slack.MessageInfo) string {
-	ProcessGroupCommandError(model.RemoveFromGroup(parts[1], parts[3:]), msg, true)
+	err := db.RemoveFromGroup(parts[1], normalizeMembers(parts[3:]))
+	if err == nil {
+		pushLinkedGroup(parts[1])
+	}
+	ProcessGroupCommandError(err, msg, true)
 	return ""
 }
 
@@ -211,6 +391,8 @@ func ProcessCommandGroup(parts []string, msg slack.MessageInfo) string {
 
 // ProcessCommandScore decides which score processing function to call
 func ProcessCommandScore(parts []string, msg slack.MessageInfo) string {
+	parts = withDefaultMatchup(parts, msg)
+
 	if len(parts) < 3 {
 		React(msg, EmojiParametersWrong)
 		return ""
@@ -268,7 +450,7 @@ func ProcessCommandScoreSet(parts []string, msg slack.MessageInfo) string {
 	score1, score2, err2 := scoresFromString(parts[3])
 	var err error
 	if err1 == nil && err2 == nil {
-		err = model.AddScore(team1, team2, score1, score2)
+		err = db.AddScore(team1, team2, score1, score2)
 		if err == nil {
 			React(msg, EmojiCommandOK)
 
@@ -298,7 +480,7 @@ func ProcessCommandScoreReset(parts []string, msg slack.MessageInfo) string {
 			score1, score2, err = scoresFromString(parts[3])
 		}
 
-		err = model.ResetScore(team1, team2, score1, score2)
+		err = db.ResetScore(team1, team2, score1, score2)
 		if err == nil {
 			React(msg, EmojiCommandOK)
 			return ""
@@ -315,12 +497,12 @@ func ProcessCommandScoreGet(parts []string, msg slack.MessageInfo) string {
 	team1, team2, err1 := teamsNamesFromString(parts[2])
 
 	if err1 == nil {
-		scoreInfo, err := model.GetScores(team1, team2)
+		scoreInfo, err := db.GetScores(team1, team2)
 
 		if err != nil {
 			React(msg, EmojiParametersWrong)
 		} else {
-			return model.GetScoreMessage(scoreInfo)
+			return model.GetScoreMessage(scoreInfo, Language(msg))
 		}
 	} else {
 		React(msg, EmojiParametersWrong)
@@ -331,112 +513,251 @@ func ProcessCommandScoreGet(parts []string, msg slack.MessageInfo) string {
 
 // ProcessCommandRandomPairs assembles random pairs
 func ProcessCommandRandomPairs(parts []string, msg slack.MessageInfo) string {
-	var returnMessage string
+	return RandomPairs(parts, msg).Text
+}
+
+// RandomPairs assembles random pairs and returns them as structured output
+func RandomPairs(parts []string, msg slack.MessageInfo) Output {
+	parts, mode := pairsMode(parts)
+
+	if len(parts) < 2 {
+		if group := db.GetChannelSettings(msg.Channel).DefaultGroup; group != "" {
+			parts = append(parts, group)
+		}
+	}
 
 	if len(parts) < 2 {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
 	members, err := getReferencedMembers(parts, 1)
 	if err != nil {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
-	teamNames, _ := model.GetGroup(PairNamesGroup)
+	teamNames, _ := db.GetGroup(PairNamesGroup)
 
-	returnMessage, err = model.GetRandomTeams(2, members, true, teamNames, false)
+	// the pairing history is kept for draws from a group or group expression
+	group := ""
+	if len(parts) == 2 {
+		group = parts[1]
+	}
+
+	draw, err := drawPairs(group, mode, members, msg)
 	if err != nil {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
-	return returnMessage
+	lang := Language(msg)
+	return Output{Text: model.FormatTeams(draw.Teams, 2, teamNames, false, lang) + formatDrawID(draw, lang), Teams: draw.Teams}
 }
 
 // ProcessCommandRandomTeams assembles random teams
 func ProcessCommandRandomTeams(parts []string, msg slack.MessageInfo) string {
-	var returnMessage string
+	return RandomTeams(parts, msg).Text
+}
+
+// RandomTeams assembles random teams and returns them as structured output
+func RandomTeams(parts []string, msg slack.MessageInfo) Output {
+	parts, balanced := balancedFlag(parts)
+	parts = withDefaultTeams(parts, msg)
 
 	if len(parts) < 3 {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
 	teamSize, err := strconv.Atoi(parts[1])
 	if err != nil {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
 	members, err := getReferencedMembers(parts, 2)
 	if err != nil {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
-	teamNames, _ := model.GetGroup(TeamNamesGroup)
+	teamNames, _ := db.GetGroup(TeamNamesGroup)
 
-	returnMessage, err = model.GetRandomTeams(teamSize, members, false, teamNames, true)
+	draw := model.Draw{Kind: model.DrawTeams, TeamSize: teamSize, Members: members}
+	if balanced {
+		draw.Mode = model.DrawModeBalanced
+		draw.Ratings, err = db.GetRatings(members)
+		if err != nil {
+			React(msg, EmojiCommandError)
+			return Output{}
+		}
+	}
+
+	draw, err = recordDraw(draw, msg)
 	if err != nil {
 		React(msg, EmojiParametersWrong)
-		return ""
+		return Output{}
 	}
 
-	return returnMessage
+	// team names are shuffled with the draw's seed, so the draw can be replayed with the same names
+	lang := Language(msg)
+	teamNames = draw.ShuffleTeamNames(teamNames)
+	return Output{Text: model.FormatTeams(draw.Teams, teamSize, teamNames, false, lang) + formatStrengths(draw, lang) + formatDrawID(draw, lang),
+		Teams: draw.Teams}
 }
 
-// ProcessCommandPut puts value at key
-func ProcessCommandPut(parts []string, msg slack.MessageInfo) string {
-	if len(parts) < 3 {
-		React(msg, EmojiParametersWrong)
+func getReferencedMembers(parts []string, offset int) ([]string, error) {
+	var members []string
+	var err error
+
+	// only one parameter means we treat it as a group name or an expression like frontend+backend-interns
+	if len(parts) == offset+1 {
+		members, err = db.ResolveGroupExpression(parts[offset])
+	} else {
+		members, err = db.ResolveMembers(parts[offset:])
+	}
+
+	if err == nil && len(VacationStatusEmojis) > 0 {
+		members = skipOnVacation(members)
+	}
+
+	return members, err
+}
+
+// ProcessCommandExport sends a JSON dump of the database to the admin in a direct message, never into the channel (admins only)
+func ProcessCommandExport(parts []string, msg slack.MessageInfo) string {
+	if !IsAdmin(msg) {
+		React(msg, EmojiCommandNotFound)
 		return ""
 	}
 
-	err := model.SetDBValue(parts[1], strings.Join(parts[2:], " "))
+	dump, err := db.ExportDatabase()
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+
+	js, err := json.MarshalIndent(dump, "", "  ")
+	var channel string
 	if err == nil {
-		React(msg, EmojiCommandOK)
-	} else {
+		channel, err = slack.OpenDirectMessage(msg.UserID)
+	}
+	if err == nil {
+		err = slack.UploadFile(channel, "hinko-"+time.Now().Format("2006-01-02")+".json", string(js))
+	}
+	if err != nil {
+		fmt.Println(err)
 		React(msg, EmojiCommandError)
+		return ""
 	}
 
+	React(msg, EmojiCommandOK)
 	return ""
 }
 
-// ProcessCommandGet gets value at key
-func ProcessCommandGet(parts []string, msg slack.MessageInfo) string {
-	var returnMessage string
+// ProcessCommandBackup writes a snapshot or lists the existing ones (admins only): backup now, backup list
+func ProcessCommandBackup(parts []string, msg slack.MessageInfo) string {
+	if !IsAdmin(msg) {
+		React(msg, EmojiCommandNotFound)
+		return ""
+	}
 
 	if len(parts) < 2 {
 		React(msg, EmojiParametersWrong)
 		return ""
 	}
 
-	data, err := model.GetDBValue(parts[1])
-	if err == nil {
-		returnMessage = data
-	} else {
-		React(msg, EmojiCommandWarning)
+	switch strings.ToLower(parts[1]) {
+	case "now":
+		s, err := db.CreateSnapshot()
+		if err == nil {
+			_, err = model.PruneSnapshots()
+		}
+		if err != nil {
+			fmt.Println(err)
+			React(msg, EmojiCommandError)
+			return ""
+		}
+		React(msg, EmojiCommandOK)
+		return "`" + s.Name + "`"
+	case "list":
+		snapshots, err := model.ListSnapshots()
+		if err != nil || len(snapshots) == 0 {
+			React(msg, EmojiCommandWarning)
+			return ""
+		}
+		ret := i18n.T(Language(msg), "backup.list")
+		for _, s := range snapshots {
+			ret += "\n`" + s.Name + "`"
+		}
+		return ret
 	}
-	return returnMessage
+
+	React(msg, EmojiParametersWrong)
+	return ""
 }
 
-func getReferencedMembers(parts []string, offset int) ([]string, error) {
-	var members []string
-	var err error
+// ProcessCommandPlugins lists the loaded external plugins and their commands
+func ProcessCommandPlugins(parts []string, msg slack.MessageInfo) string {
+	loaded := plugins.List()
+	if len(loaded) == 0 {
+		return i18n.T(Language(msg), "plugins.none")
+	}
 
-	// only one parameter means we treat it as a group name
-	if len(parts) == offset+1 {
-		members, err = model.GetGroup(parts[offset])
-	} else {
-		members = parts[offset:]
+	ret := i18n.T(Language(msg), "plugins.loaded")
+	for _, p := range loaded {
+		ret += "\n*" + p.Name + "* `" + strings.Join(p.Commands, "` `") + "`"
+		if p.Description != "" {
+			ret += " " + p.Description
+		}
 	}
 
-	return members, err
+	return ret
+}
+
+// ProcessCommandPlugin runs the external plugin registered for parts[0]
+func ProcessCommandPlugin(parts []string, msg slack.MessageInfo) string {
+	p := plugins.Find(parts[0])
+	if p == nil {
+		React(msg, EmojiCommandNotFound)
+		return ""
+	}
+
+	kv := make(map[string]string)
+	for _, key := range p.KVKeys {
+		value, err := db.GetKVValue(model.GlobalScope, key)
+		if err == nil {
+			kv[key] = value
+		}
+	}
+
+	resp, err := p.Run(plugins.NewRequest(parts, msg, kv))
+	if err != nil {
+		fmt.Println(err)
+		React(msg, EmojiCommandError)
+		return ""
+	}
+
+	for key, value := range resp.KV {
+		err = db.SetKVValue(model.GlobalScope, key, value, msg.UserID)
+		auditWrite(msg, "put "+key+" "+value+" ("+p.Name+" plugin)", err)
+		if err != nil {
+			React(msg, EmojiCommandError)
+		}
+	}
+
+	for _, reaction := range resp.Reactions {
+		React(msg, reaction)
+	}
+
+	return resp.Text
 }
 
 // React adds Slack Reaction (Emoji)
 func React(msg slack.MessageInfo, Reaction string) {
+	if recordReaction(msg, Reaction) {
+		return
+	}
 	slack.AddReaction(msg.Username, msg.Channel, msg.Timestamp, Reaction)
 }
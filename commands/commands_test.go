@@ -0,0 +1,218 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// useTestDatabase makes the commands use an empty in-memory database until the test ends
+func useTestDatabase(t *testing.T) {
+	previous := db
+	UseDatabase(model.NewDB(model.NewMemoryStore()))
+	t.Cleanup(func() {
+		db.Close()
+		UseDatabase(previous)
+	})
+}
+
+// TestSplitLines tests splitting a message into batch commands
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"get a", []string{"get a"}},
+		{"get a; get b\nget c", []string{"get a", "get b", "get c"}},
+		{" ; \n get a ;", []string{"get a"}},
+		{"put site <https://example.com/?a=1;b=2>", []string{"put site <https://example.com/?a=1;b=2>"}},
+		{"put law tom &amp; jerry; get law", []string{"put law tom &amp; jerry", "get law"}},
+		{"put cmp 1 &lt; 2 &gt; 0", []string{"put cmp 1 &lt; 2 &gt; 0"}},
+		{"put quote it&#39;s; get quote", []string{"put quote it&#39;s", "get quote"}},
+		{`put motto work\; play; get motto`, []string{"put motto work; play", "get motto"}},
+		{"put odd a &b; c", []string{"put odd a &b", "c"}},
+	}
+
+	for _, test := range tests {
+		if got := splitLines(test.message); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitLines(%q) = %q, expected %q", test.message, got, test.want)
+		}
+	}
+}
+
+// TestStopOnErrorFlag tests that the batch option is only recognized as a whole first word
+func TestStopOnErrorFlag(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+		stop    bool
+	}{
+		{"--stop-on-error get a; get b", "get a; get b", true},
+		{" --stop-on-error\nget a\nget b", "get a\nget b", true},
+		{"--stop-on-error", "", true},
+		{"--stop-on-errors get a", "--stop-on-errors get a", false},
+		{"get a; --stop-on-error", "get a; --stop-on-error", false},
+	}
+
+	for _, test := range tests {
+		got, stop := stopOnErrorFlag(test.message)
+		if got != test.want || stop != test.stop {
+			t.Errorf("stopOnErrorFlag(%q) = %q, %v, expected %q, %v", test.message, got, stop, test.want, test.stop)
+		}
+	}
+}
+
+// TestParsePipeline tests splitting a command into pipeline stages and a redirection
+func TestParsePipeline(t *testing.T) {
+	tests := []struct {
+		message  string
+		stages   []string
+		redirect string
+	}{
+		{"group a list", []string{"group a list"}, ""},
+		{"group a list | randompairs", []string{"group a list ", " randompairs"}, ""},
+		{"group a list | randomteams 2 > teams", []string{"group a list ", " randomteams 2 "}, "teams"},
+		{"group a list &gt; #here teams", []string{"group a list "}, "#here teams"},
+		{"put hi <@U1|anne> <https://x.io|x>", []string{"put hi <@U1|anne> <https://x.io|x>"}, ""},
+		{`put op a \| b \&gt; c`, []string{"put op a | b &gt; c"}, ""},
+		{`put op a \> b`, []string{"put op a > b"}, ""},
+		{"put law tom &amp; jerry", []string{"put law tom &amp; jerry"}, ""},
+	}
+
+	for _, test := range tests {
+		stages, redirect := parsePipeline(test.message)
+		if !reflect.DeepEqual(stages, test.stages) || redirect != test.redirect {
+			t.Errorf("parsePipeline(%q) = %q, %q, expected %q, %q", test.message, stages, redirect, test.stages, test.redirect)
+		}
+	}
+}
+
+// TestWithDefaultMatchup tests which score commands get the channel's default matchup
+func TestWithDefaultMatchup(t *testing.T) {
+	useTestDatabase(t)
+	msg := slack.MessageInfo{Channel: "C1"}
+	_ = db.SetChannelSetting("C1", model.SettingMatchup, "red:blue")
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"score get", "score get RED:BLUE"},
+		{"score add 10:8", "score add RED:BLUE 10:8"},
+		{"score add 10:8 *", "score add RED:BLUE 10:8 *"},
+		{"score add 1:2 10:8", "score add 1:2 10:8"},
+		{"score add green:blue 10:8", "score add green:blue 10:8"},
+		{"score get green:blue", "score get green:blue"},
+		{"score reset", "score reset"},
+		{"score reset 10:8", "score reset 10:8"},
+	}
+
+	for _, test := range tests {
+		got := strings.Join(withDefaultMatchup(strings.Fields(test.command), msg), " ")
+		if got != test.want {
+			t.Errorf("withDefaultMatchup(%q) = %q, expected %q", test.command, got, test.want)
+		}
+	}
+}
+
+// TestRedirectAudited tests that a pipeline redirection stores the output and writes it to the audit log
+func TestRedirectAudited(t *testing.T) {
+	useTestDatabase(t)
+	msg := slack.MessageInfo{UserID: "U1", Channel: "C1", Timestamp: "1"}
+	_ = db.SetKVValue(model.GlobalScope, "greeting", "hello", "U1")
+
+	startRecording(msg)
+	executePipeline("get greeting > copy", msg)
+	reactions := stopRecording(msg)
+
+	if value, err := db.GetKVValue(model.GlobalScope, "copy"); err != nil || value != "hello" {
+		t.Errorf("Expected the output to be stored, got %q (%v), reactions %v", value, err, reactions)
+	}
+
+	records, _ := db.GetAuditRecords(time.Time{}, nil)
+	if len(records) != 1 || records[0].Command != "put copy hello" || records[0].Result != "ok" {
+		t.Errorf("Expected the redirection to be audited, got %+v", records)
+	}
+}
+
+// TestKarmaCooldown tests that karma can be changed once per cooldown and that ended cooldowns are dropped
+func TestKarmaCooldown(t *testing.T) {
+	cooldown, given := KarmaCooldown, lastKarmaGiven
+	t.Cleanup(func() {
+		KarmaCooldown, lastKarmaGiven = cooldown, given
+	})
+
+	KarmaCooldown = time.Hour
+	lastKarmaGiven = map[string]time.Time{"U1 old": time.Now().Add(-2 * time.Hour)}
+
+	if !karmaAllowed("U1", "coffee") || karmaAllowed("U1", "coffee") {
+		t.Error("Expected karma to be allowed once per cooldown")
+	}
+	if !karmaAllowed("U2", "coffee") {
+		t.Error("Expected the cooldown to be per giver")
+	}
+	if _, ok := lastKarmaGiven["U1 old"]; ok || len(lastKarmaGiven) != 2 {
+		t.Errorf("Expected ended cooldowns to be dropped, got %v", lastKarmaGiven)
+	}
+}
+
+// TestGroupMentionForms tests that members given as <@U1|name>, the form Slack sends, can be added, paused, resumed and removed
+func TestGroupMentionForms(t *testing.T) {
+	useTestDatabase(t)
+
+	statuses, fetched := userDirectory.statuses, userDirectory.fetched
+	t.Cleanup(func() {
+		userDirectory.statuses, userDirectory.fetched = statuses, fetched
+	})
+	userDirectory.statuses = map[string]string{"U1": "", "U2": ""}
+	userDirectory.fetched = time.Now()
+	msg := slack.MessageInfo{UserID: "U1", Channel: "C1", Timestamp: "1"}
+
+	startRecording(msg)
+	defer stopRecording(msg)
+
+	ProcessCommandGroupAdd(strings.Fields("group devs add <@U1|bob> <@U2|ana>"), msg)
+	if members, _ := db.GetGroup("devs"); strings.Join(members, " ") != "<@U1> <@U2>" {
+		t.Fatalf("Expected <@U1> <@U2>, got %v", members)
+	}
+
+	ProcessCommandGroupPause(strings.Fields("group devs pause <@U1|bob>"), msg)
+	if group, _ := db.GetGroupInfo("devs"); len(group.PausedMembers()) != 1 {
+		t.Errorf("Expected <@U1> to be paused, got %v", group.Paused)
+	}
+	ProcessCommandGroupResume(strings.Fields("group devs resume <@U1|bob>"), msg)
+	if group, _ := db.GetGroupInfo("devs"); len(group.PausedMembers()) != 0 {
+		t.Errorf("Expected <@U1> to be resumed, got %v", group.Paused)
+	}
+
+	ProcessCommandGroupRemove(strings.Fields("group devs remove <@U1|bob>"), msg)
+	if members, _ := db.GetGroup("devs"); strings.Join(members, " ") != "<@U2>" {
+		t.Errorf("Expected <@U2>, got %v", members)
+	}
+}
@@ -0,0 +1,97 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// recordDraw draws the teams of draw with a new seed and records it so it can be verified later
+func recordDraw(draw model.Draw, msg slack.MessageInfo) (model.Draw, error) {
+	draw.Seed = model.NewSeed()
+	draw.UserID = msg.UserID
+	draw.Channel = msg.Channel
+
+	teams, err := draw.Replay()
+	if err != nil {
+		return draw, err
+	}
+	draw.Teams = teams
+
+	draw, err = db.RecordDraw(draw)
+	if err != nil {
+		// the draw itself is fine, it just can't be verified later
+		fmt.Printf("Recording draw, %s\n", err)
+		draw.ID = ""
+	}
+
+	return draw, nil
+}
+
+// formatDrawID returns a line with the ID of a recorded draw, or "" if it wasn't recorded
+func formatDrawID(draw model.Draw, lang string) string {
+	if draw.ID == "" {
+		return ""
+	}
+	return "\n" + i18n.T(lang, "draw.id", draw.ID)
+}
+
+// ProcessCommandVerify replays a recorded draw from its seed and members and checks it gives the same teams: verify 1a2b3c4d
+func ProcessCommandVerify(parts []string, msg slack.MessageInfo) string {
+	if len(parts) != 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	draw, err := db.GetDraw(strings.ToLower(parts[1]))
+	if err != nil {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
+	ok, err := draw.Verify()
+	if err != nil || !ok {
+		React(msg, EmojiCommandError)
+	} else {
+		React(msg, EmojiCommandOK)
+	}
+
+	lang := Language(msg)
+	result := i18n.T(lang, "draw.mismatch")
+	if ok {
+		result = i18n.T(lang, "draw.match")
+	}
+
+	var teams []string
+	for _, t := range draw.Teams {
+		teams = append(teams, strings.Join(t, " "))
+	}
+
+	return i18n.T(lang, "draw.verify", draw.ID, draw.Kind, "<@"+draw.UserID+">", draw.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		draw.Seed, strings.Join(draw.Members, " "), strings.Join(teams, " | "), result)
+}
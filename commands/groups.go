@@ -0,0 +1,547 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// formatGroupTime returns an RFC3339 group timestamp as a short local date and time
+func formatGroupTime(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// ProcessCommandGroups lists all groups with their member count and description
+func ProcessCommandGroups(parts []string, msg slack.MessageInfo) string {
+	lang := Language(msg)
+
+	groups, err := db.ListGroups()
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+	if len(groups) == 0 {
+		return i18n.T(lang, "groups.none")
+	}
+
+	ret := i18n.T(lang, "groups.title")
+	for _, g := range groups {
+		members, _ := db.ResolveGroup(g.Name)
+		ret += "\n" + strings.TrimSpace(i18n.N(lang, "groups.entry", len(members), g.Name, len(members), g.Description))
+	}
+
+	return ret
+}
+
+// ProcessCommandGroupInfo shows the owner, description, member count and timestamps of a group
+func ProcessCommandGroupInfo(parts []string, msg slack.MessageInfo) string {
+	lang := Language(msg)
+
+	group, err := db.GetGroupInfo(parts[1])
+	if err != nil {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
+	members, _ := db.ResolveGroup(group.Name)
+
+	owner := "-"
+	if group.Owner != "" {
+		owner = "<@" + group.Owner + ">"
+	}
+
+	return i18n.T(lang, "group.info", group.Name, group.Description, i18n.N(lang, "group.count", len(members), len(members)),
+		owner, formatGroupTime(group.Created), formatGroupTime(group.Updated)) + formatLink(group, lang)
+}
+
+// formatLink returns lines describing the Slack user group link of group and its conflicts, or "" if it isn't linked
+func formatLink(group model.GroupInfo, lang string) string {
+	if group.Link == nil {
+		return ""
+	}
+
+	mode := i18n.T(lang, "group.link.pull")
+	if group.Link.Push {
+		mode = i18n.T(lang, "group.link.push")
+	}
+
+	ret := "\n" + i18n.T(lang, "group.link", "<!subteam^"+group.Link.SlackGroup+">", mode, formatGroupTime(group.Link.Synced))
+
+	onlyLocal, onlyRemote := group.LinkConflicts()
+	if len(onlyLocal) > 0 || len(onlyRemote) > 0 {
+		ret += "\n" + i18n.T(lang, "group.link.conflicts", formatMentions(onlyLocal), formatMentions(onlyRemote))
+	}
+	if group.Link.Error != "" {
+		ret += "\n" + i18n.T(lang, "group.link.error", group.Link.Error)
+	}
+
+	return ret
+}
+
+func formatMentions(ids []string) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+
+	var mentions []string
+	for _, id := range ids {
+		mentions = append(mentions, "<@"+id+">")
+	}
+	return strings.Join(mentions, " ")
+}
+
+// subteamFromMention returns S123 for a Slack user group mention such as <!subteam^S123|@team> or a plain ID
+func subteamFromMention(mention string) (string, bool) {
+	if !strings.HasPrefix(mention, model.SubteamMentionPrefix) {
+		return mention, mention != "" && !strings.ContainsAny(mention, "<>@|")
+	}
+	return model.MentionID(mention, model.SubteamMentionPrefix)
+}
+
+// syncLinkedGroup merges the members of the Slack user group linked to group into it, pushing changes back for two-way links
+func syncLinkedGroup(group model.GroupInfo) error {
+	slackGroup := group.Link.SlackGroup
+
+	remote, err := slack.GetUserGroupMembers(slackGroup)
+	if err != nil {
+		return err
+	}
+
+	return db.SyncGroup(group.Name, remote, func(members []string) error {
+		return slack.SetUserGroupMembers(slackGroup, members)
+	})
+}
+
+// pushLinkedGroup syncs group name in the background if it has a two-way Slack user group link
+func pushLinkedGroup(name string) {
+	group, err := db.GetGroupInfo(name)
+	if err != nil || group.Link == nil || !group.Link.Push {
+		return
+	}
+
+	go func() {
+		if err := syncLinkedGroup(group); err != nil {
+			fmt.Printf("Syncing group %s, %s\n", name, err)
+		}
+	}()
+}
+
+// SyncSlackGroup syncs the groups linked to Slack user group subteamID, or all linked groups if it is empty
+func SyncSlackGroup(subteamID string) {
+	groups, err := db.GetLinkedGroups(subteamID)
+	if err != nil {
+		fmt.Printf("Listing linked groups, %s\n", err)
+		return
+	}
+
+	for _, g := range groups {
+		if err := syncLinkedGroup(g); err != nil {
+			fmt.Printf("Syncing group %s, %s\n", g.Name, err)
+		}
+	}
+}
+
+// SyncSlackGroupsLoop syncs all linked groups every interval. Run it in a goroutine
+func SyncSlackGroupsLoop(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		SyncSlackGroup("")
+	}
+}
+
+// LinkReplaceOption lets a one-way link remove the group's members that aren't in the Slack user group
+var LinkReplaceOption = "--replace"
+
+// ProcessCommandGroupLink links a group to a Slack user group: group backend link @backend-team [--push] [--replace].
+// A one-way link replaces the group's members, so the members it would remove are listed instead unless --replace is given
+func ProcessCommandGroupLink(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 4 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	push, replace := false, false
+	for _, option := range parts[4:] {
+		switch strings.ToLower(option) {
+		case "--push":
+			push = true
+		case LinkReplaceOption:
+			replace = true
+		default:
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+	}
+
+	slackGroup, ok := subteamFromMention(parts[3])
+	if !ok {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	if !push && !replace {
+		removed, err := linkRemovals(parts[1], slackGroup)
+		if err != nil {
+			fmt.Printf("Linking group %s, %s\n", parts[1], err)
+			ProcessGroupCommandError(err, msg, true)
+			return ""
+		}
+		if len(removed) > 0 {
+			React(msg, EmojiCommandWarning)
+			return i18n.T(Language(msg), "group.link.replace", formatMentions(removed), parts[1])
+		}
+	}
+
+	err := db.LinkGroup(parts[1], slackGroup, push)
+	if err == nil {
+		var group model.GroupInfo
+		group, err = db.GetGroupInfo(parts[1])
+		if err == nil {
+			err = syncLinkedGroup(group)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Linking group %s, %s\n", parts[1], err)
+	}
+
+	ProcessGroupCommandError(err, msg, true)
+	return ""
+}
+
+// linkRemovals returns the IDs of the members of group name that a one-way link to slackGroup would remove
+func linkRemovals(name string, slackGroup string) ([]string, error) {
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := slack.GetUserGroupMembers(slackGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, id := range model.UserIDs(group.Members) {
+		if !model.Contains(remote, id) {
+			removed = append(removed, id)
+		}
+	}
+	return removed, nil
+}
+
+// ProcessCommandGroupUnlink removes the Slack user group link of a group
+func ProcessCommandGroupUnlink(parts []string, msg slack.MessageInfo) string {
+	ProcessGroupCommandError(db.UnlinkGroup(parts[1]), msg, true)
+	return ""
+}
+
+// ProcessCommandGroupSync syncs a linked group with its Slack user group right away
+func ProcessCommandGroupSync(parts []string, msg slack.MessageInfo) string {
+	group, err := db.GetGroupInfo(parts[1])
+	if err != nil || group.Link == nil {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
+	err = syncLinkedGroup(group)
+	if err != nil {
+		fmt.Printf("Syncing group %s, %s\n", group.Name, err)
+	}
+
+	ProcessGroupCommandError(err, msg, true)
+	return ""
+}
+
+// ProcessCommandGroupDelete deletes a group
+func ProcessCommandGroupDelete(parts []string, msg slack.MessageInfo) string {
+	ProcessGroupCommandError(db.DeleteGroup(parts[1]), msg, true)
+	return ""
+}
+
+// ProcessCommandGroupRename renames a group: group X rename Y
+func ProcessCommandGroupRename(parts []string, msg slack.MessageInfo) string {
+	if len(parts) != 4 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	ProcessGroupCommandError(db.RenameGroup(parts[1], parts[3]), msg, true)
+	return ""
+}
+
+// ProcessCommandGroupCopy copies a group: group X copy Y
+func ProcessCommandGroupCopy(parts []string, msg slack.MessageInfo) string {
+	if len(parts) != 4 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	ProcessGroupCommandError(db.CopyGroup(parts[1], parts[3], msg.UserID), msg, true)
+	return ""
+}
+
+// ProcessCommandGroupDescribe sets the description of a group: group X describe "text"
+func ProcessCommandGroupDescribe(parts []string, msg slack.MessageInfo) string {
+	description := strings.Trim(strings.Join(parts[3:], " "), "\"“” ")
+
+	ProcessGroupCommandError(db.DescribeGroup(parts[1], description), msg, true)
+	return ""
+}
+
+// AllowNonUsersFlag lets group create and add store members that aren't workspace users, e.g. team names
+const AllowNonUsersFlag = "--allow-non-users"
+
+// UserDirectoryTTL - how long the list of workspace users and their status emojis is cached
+var UserDirectoryTTL = 10 * time.Minute
+
+var userDirectory struct {
+	sync.Mutex
+	statuses map[string]string
+	fetched  time.Time
+}
+
+// refreshUserDirectory fetches the workspace users and their status emojis if the cached ones are older than UserDirectoryTTL.
+// It returns false if there is no directory because it couldn't be fetched. Call it with userDirectory locked
+func refreshUserDirectory() bool {
+	if userDirectory.statuses == nil || time.Since(userDirectory.fetched) > UserDirectoryTTL {
+		statuses, err := slack.GetUserStatusEmojis()
+		if err != nil {
+			fmt.Printf("Fetching users, %s\n", err)
+			return userDirectory.statuses != nil
+		}
+
+		userDirectory.statuses = statuses
+		userDirectory.fetched = time.Now()
+	}
+
+	return true
+}
+
+// isWorkspaceUser returns true if id belongs to an active workspace user. If the directory can't be fetched every ID is accepted
+func isWorkspaceUser(id string) bool {
+	userDirectory.Lock()
+	defer userDirectory.Unlock()
+
+	if !refreshUserDirectory() {
+		return true
+	}
+
+	_, ok := userDirectory.statuses[id]
+	return ok
+}
+
+// normalizeMember returns a member in the form groups store it: <@U1|bob> becomes <@U1>, other tokens stay as they are
+func normalizeMember(token string) string {
+	if userID, ok := model.MentionID(token, model.UserMentionPrefix); ok {
+		return "<@" + userID + ">"
+	}
+	return token
+}
+
+// normalizeMembers returns tokens with every member normalized by normalizeMember
+func normalizeMembers(tokens []string) []string {
+	var members []string
+	for _, t := range tokens {
+		members = append(members, normalizeMember(t))
+	}
+	return members
+}
+
+// validateMembers returns the valid members among tokens without duplicates and the rejected tokens.
+// Mentions of workspace users and %group references are valid, other tokens only if allowNonUsers is set
+func validateMembers(tokens []string, allowNonUsers bool) ([]string, []string) {
+	var valid []string
+	var rejected []string
+
+	for _, t := range tokens {
+		member := normalizeMember(t)
+		ok := allowNonUsers
+
+		if userID, isMention := model.MentionID(t, model.UserMentionPrefix); isMention {
+			ok = isWorkspaceUser(userID)
+		} else if strings.HasPrefix(t, model.GroupRefPrefix) && len(t) > len(model.GroupRefPrefix) {
+			ok = true
+		}
+
+		switch {
+		case t == "":
+		case !ok:
+			rejected = append(rejected, t)
+		case !model.Contains(valid, member):
+			valid = append(valid, member)
+		}
+	}
+
+	return valid, rejected
+}
+
+// changeGroupMembers validates the members in parts[3:] and passes the valid ones to fn, reporting rejected tokens
+func changeGroupMembers(parts []string, msg slack.MessageInfo, fn func(string, []string, string) error) string {
+	name := parts[1]
+
+	var tokens []string
+	allowFlag := false
+	for _, p := range parts[3:] {
+		if strings.ToLower(p) == AllowNonUsersFlag {
+			allowFlag = true
+		} else {
+			tokens = append(tokens, p)
+		}
+	}
+
+	allow := allowFlag || name == TeamNamesGroup || name == PairNamesGroup
+	if group, err := db.GetGroupInfo(name); err == nil && group.AllowNonUsers {
+		allow = true
+	}
+
+	valid, rejected := validateMembers(tokens, allow)
+
+	ret := ""
+	if len(rejected) > 0 {
+		ret = i18n.T(Language(msg), "group.rejected", strings.Join(rejected, " "))
+		if len(valid) == 0 {
+			React(msg, EmojiParametersWrong)
+			return ret
+		}
+	}
+
+	err := fn(name, valid, msg.UserID)
+	if err == nil && allowFlag {
+		err = db.SetGroupAllowNonUsers(name, true)
+	}
+	if err == nil {
+		pushLinkedGroup(name)
+	}
+
+	ProcessGroupCommandError(err, msg, true)
+	return ret
+}
+
+// VacationStatusEmojis - Slack status emojis (e.g. :palm_tree:) that exclude a user from draws
+var VacationStatusEmojis []string
+
+func isVacationEmoji(emoji string) bool {
+	emoji = strings.Trim(emoji, ":")
+	for _, e := range VacationStatusEmojis {
+		if emoji != "" && strings.Trim(e, ":") == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// skipOnVacation returns members without the users whose Slack status emoji is one of VacationStatusEmojis.
+// Statuses come from the user directory, so they can be up to UserDirectoryTTL old
+func skipOnVacation(members []string) []string {
+	userDirectory.Lock()
+	defer userDirectory.Unlock()
+
+	if !refreshUserDirectory() {
+		return members
+	}
+
+	var ret []string
+	for _, m := range members {
+		userID, ok := model.MentionID(m, model.UserMentionPrefix)
+		if ok && isVacationEmoji(userDirectory.statuses[userID]) {
+			continue
+		}
+		ret = append(ret, m)
+	}
+
+	return ret
+}
+
+// formatPaused returns a line listing the paused members of group, or "" if there are none
+func formatPaused(group model.GroupInfo, lang string) string {
+	paused := group.PausedMembers()
+	if len(paused) == 0 {
+		return ""
+	}
+
+	var names []string
+	for m := range paused {
+		names = append(names, m)
+	}
+	sort.Strings(names)
+
+	var list []string
+	for _, m := range names {
+		if paused[m] == "" {
+			list = append(list, m)
+		} else {
+			list = append(list, i18n.T(lang, "group.paused.until", m, paused[m]))
+		}
+	}
+
+	return "\n" + i18n.T(lang, "group.paused", strings.Join(list, ", "))
+}
+
+// ProcessCommandGroupPause pauses members so draws skip them: group devs pause @ana [until 2026-10-28]
+func ProcessCommandGroupPause(parts []string, msg slack.MessageInfo) string {
+	members := parts[3:]
+	until := ""
+
+	for i, p := range members {
+		if strings.ToLower(p) == "until" {
+			if i != len(members)-2 {
+				React(msg, EmojiParametersWrong)
+				return ""
+			}
+			until = members[i+1]
+			members = members[:i]
+			break
+		}
+	}
+
+	if len(members) == 0 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	ProcessGroupCommandError(db.PauseMembers(parts[1], normalizeMembers(members), until), msg, true)
+	return ""
+}
+
+// ProcessCommandGroupResume makes paused members active again: group devs resume @ana
+func ProcessCommandGroupResume(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 4 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	ProcessGroupCommandError(db.ResumeMembers(parts[1], normalizeMembers(parts[3:])), msg, true)
+	return ""
+}
@@ -0,0 +1,183 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// KarmaCooldown is how long a user has to wait before changing the karma of the same user or thing again
+var KarmaCooldown = time.Minute
+
+// KarmaTopSize is the number of entries shown by karma top
+var KarmaTopSize = 10
+
+// matches a word such as <@U123>++ or thing-- (trailing punctuation is trimmed first)
+var karmaPattern = regexp.MustCompile(`^(<@[A-Z0-9]+(?:\|[^>]*)?>|[\p{L}\p{N}_.\-]*[\p{L}\p{N}_])(\+\+|--)$`)
+
+var (
+	karmaMutex     sync.Mutex
+	lastKarmaGiven = make(map[string]time.Time)
+)
+
+// karmaName normalizes a karma target: mentions become <@U123>, everything else is lower case
+func karmaName(target string) string {
+	if userID, ok := model.MentionID(target, model.UserMentionPrefix); ok {
+		return "<@" + userID + ">"
+	}
+	return strings.ToLower(target)
+}
+
+// karmaAllowed reports whether giver may change the karma of name now, and starts the cooldown if so.
+// Cooldowns that have ended are dropped, so the map only holds recent changes
+func karmaAllowed(giver string, name string) bool {
+	karmaMutex.Lock()
+	defer karmaMutex.Unlock()
+
+	key := giver + " " + name
+	if t, ok := lastKarmaGiven[key]; ok && time.Since(t) < KarmaCooldown {
+		return false
+	}
+
+	for k, t := range lastKarmaGiven {
+		if time.Since(t) >= KarmaCooldown {
+			delete(lastKarmaGiven, k)
+		}
+	}
+	lastKarmaGiven[key] = time.Now()
+
+	return true
+}
+
+// ProcessKarma applies every @user++ and thing-- in a message and returns the new standings
+func ProcessKarma(text string, msg slack.MessageInfo) string {
+	ret := ""
+	lang := Language(msg)
+
+	for _, word := range strings.Fields(text) {
+		match := karmaPattern.FindStringSubmatch(strings.TrimRight(word, ".,!?:;"))
+		if match == nil {
+			continue
+		}
+
+		name := karmaName(match[1])
+
+		// no karma for yourself
+		if name == "<@"+msg.UserID+">" || !karmaAllowed(msg.UserID, name) {
+			continue
+		}
+
+		delta := 1
+		if match[2] == "--" {
+			delta = -1
+		}
+
+		karma, err := db.AddKarma(name, delta)
+		if err != nil {
+			continue
+		}
+
+		if ret != "" {
+			ret += "\n"
+		}
+		ret += i18n.T(lang, "karma.changed", name, karma)
+	}
+
+	return ret
+}
+
+// ProcessCommandKarma shows the karma leaderboard or the karma of one user or thing: karma top, karma @user
+func ProcessCommandKarma(parts []string, msg slack.MessageInfo) string {
+	lang := Language(msg)
+
+	if len(parts) < 2 || strings.ToLower(parts[1]) == "top" {
+		entries, err := db.GetKarmaTop(KarmaTopSize)
+		if err != nil {
+			React(msg, EmojiCommandError)
+			return ""
+		}
+		if len(entries) == 0 {
+			React(msg, EmojiCommandWarning)
+			return ""
+		}
+
+		ret := i18n.T(lang, "karma.top")
+		for i, e := range entries {
+			ret += "\n" + strconv.Itoa(i+1) + ". " + e.Name + " " + strconv.Itoa(e.Karma)
+		}
+		return ret
+	}
+
+	name := karmaName(strings.Join(parts[1:], " "))
+	return i18n.T(lang, "karma.current", name, db.GetKarma(name))
+}
+
+func processCounter(parts []string, msg slack.MessageInfo, sign int) string {
+	if len(parts) < 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	delta := 1
+
+	// a trailing number is the amount, unless it's the only parameter
+	if len(parts) > 2 {
+		n, err := strconv.Atoi(parts[len(parts)-1])
+		if err == nil {
+			delta = n
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	scope, args := parseKVScope(parts, 1, msg)
+	if len(args) != 1 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	n, err := db.IncrKVValue(scope, args[0], sign*delta, msg.UserID)
+	if err != nil {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	return args[0] + ": " + strconv.Itoa(n)
+}
+
+// ProcessCommandIncr atomically increments a counter: incr [#here|me] key [n]
+func ProcessCommandIncr(parts []string, msg slack.MessageInfo) string {
+	return processCounter(parts, msg, 1)
+}
+
+// ProcessCommandDecr atomically decrements a counter: decr [#here|me] key [n]
+func ProcessCommandDecr(parts []string, msg slack.MessageInfo) string {
+	return processCounter(parts, msg, -1)
+}
@@ -0,0 +1,363 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// ChannelScopeKeyword selects the current channel's key-value namespace
+var ChannelScopeKeyword = "#here"
+
+// UserScopeKeyword selects the calling user's key-value namespace
+var UserScopeKeyword = "me"
+
+// scopeFromToken returns the scope selected by #here, <#C123|name> or me
+func scopeFromToken(token string, msg slack.MessageInfo) (model.KVScope, bool) {
+	switch {
+	case token == ChannelScopeKeyword:
+		return model.ChannelScope(msg.Channel), true
+	case strings.ToLower(token) == UserScopeKeyword:
+		return model.UserScope(msg.UserID), true
+	case strings.HasPrefix(token, "<#") && strings.HasSuffix(token, ">"):
+		channel := strings.TrimSuffix(strings.TrimPrefix(token, "<#"), ">")
+		if i := strings.Index(channel, "|"); i >= 0 {
+			channel = channel[:i]
+		}
+		return model.ChannelScope(channel), true
+	}
+
+	return model.GlobalScope, false
+}
+
+// parseKVScope reads an optional scope at parts[offset] and returns it with the remaining parts.
+// A lone last parameter is always treated as a key
+func parseKVScope(parts []string, offset int, msg slack.MessageInfo) (model.KVScope, []string) {
+	if len(parts) <= offset+1 {
+		return model.GlobalScope, parts[offset:]
+	}
+
+	if scope, ok := scopeFromToken(parts[offset], msg); ok {
+		return scope, parts[offset+1:]
+	}
+
+	return model.GlobalScope, parts[offset:]
+}
+
+// TTLOption sets an expiry time on a value: put --ttl 2h key value
+var TTLOption = "--ttl"
+
+// KeysPageSize is the number of keys listed per page
+var KeysPageSize = 20
+
+// parseTTL parses durations such as 90s, 15m, 2h and 3d
+func parseTTL(input string) (time.Duration, error) {
+	if strings.HasSuffix(input, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(input, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(input)
+}
+
+// FactoidTrigger enables answering "?key" in any channel without mentioning the bot
+var FactoidTrigger = false
+
+// FactoidTriggerPrefix starts a factoid lookup when FactoidTrigger is enabled
+var FactoidTriggerPrefix = "?"
+
+func templateContext(msg slack.MessageInfo) model.TemplateContext {
+	return model.TemplateContext{UserID: msg.UserID, Channel: msg.Channel, Now: time.Now()}
+}
+
+// ProcessFactoidTrigger answers "?key" with the channel's or the global value of key. Unknown keys are ignored silently
+func ProcessFactoidTrigger(text string, msg slack.MessageInfo) string {
+	if !FactoidTrigger || !strings.HasPrefix(text, FactoidTriggerPrefix) {
+		return ""
+	}
+
+	key := strings.TrimPrefix(text, FactoidTriggerPrefix)
+	if key == "" || strings.ContainsAny(key, " \n") {
+		return ""
+	}
+
+	data, err := db.GetKVValue(model.ChannelScope(msg.Channel), key)
+	if err != nil {
+		data, err = db.GetKVValue(model.GlobalScope, key)
+	}
+	if err != nil {
+		return ""
+	}
+
+	return db.RenderFactoid(data, templateContext(msg))
+}
+
+// VariantOption adds the value as a factoid variant instead of replacing it: put key --variant text
+const VariantOption = "--variant"
+
+// ProcessCommandPut puts value at key: put [--ttl 2h] [#here|me] key [--variant] value
+func ProcessCommandPut(parts []string, msg slack.MessageInfo) string {
+	var ttl time.Duration
+	var err error
+
+	offset := 1
+	if len(parts) > 2 && strings.ToLower(parts[1]) == TTLOption {
+		ttl, err = parseTTL(parts[2])
+		if err != nil || ttl <= 0 {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+		offset = 3
+	}
+
+	if len(parts) < offset+2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	scope, args := parseKVScope(parts, offset, msg)
+	if len(args) < 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	variant := strings.ToLower(args[1]) == VariantOption
+	if variant && (len(args) < 3 || ttl > 0) {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	value := strings.Join(args[1:], " ")
+
+	if variant {
+		err = db.AddKVVariant(scope, args[0], strings.Join(args[2:], " "), msg.UserID)
+	} else if ttl > 0 {
+		err = db.SetKVValueWithTTL(scope, args[0], value, msg.UserID, ttl)
+	} else {
+		err = db.SetKVValue(scope, args[0], value, msg.UserID)
+	}
+	if err == nil {
+		React(msg, EmojiCommandOK)
+	} else {
+		React(msg, EmojiCommandError)
+	}
+
+	return ""
+}
+
+// splitVersion splits key@3 into key and version 3. Keys without a numeric suffix return version 0
+func splitVersion(input string) (string, int) {
+	i := strings.LastIndex(input, "@")
+	if i <= 0 {
+		return input, 0
+	}
+
+	version, err := strconv.Atoi(input[i+1:])
+	if err != nil || version < 1 {
+		return input, 0
+	}
+
+	return input[:i], version
+}
+
+// ProcessCommandGet gets value at key: get [#here|me] key[@version]
+func ProcessCommandGet(parts []string, msg slack.MessageInfo) string {
+	var returnMessage string
+	var data string
+	var err error
+
+	if len(parts) < 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	scope, args := parseKVScope(parts, 1, msg)
+
+	key, version := splitVersion(args[0])
+	if version > 0 {
+		var v model.KVVersion
+		v, err = db.GetKVVersion(scope, key, version)
+		data = v.Value
+	} else {
+		data, err = db.GetKVValue(scope, args[0])
+	}
+	if err == nil {
+		returnMessage = db.RenderFactoid(data, templateContext(msg))
+	} else {
+		React(msg, EmojiCommandWarning)
+	}
+	return returnMessage
+}
+
+// ProcessCommandDel deletes a key: del [#here|me] key
+func ProcessCommandDel(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	scope, args := parseKVScope(parts, 1, msg)
+
+	err := db.DeleteKVValue(scope, args[0])
+	if err == nil {
+		React(msg, EmojiCommandOK)
+	} else {
+		React(msg, EmojiCommandWarning)
+	}
+
+	return ""
+}
+
+// ProcessCommandExists reacts with a check mark if a key exists: exists [#here|me] key
+func ProcessCommandExists(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	scope, args := parseKVScope(parts, 1, msg)
+
+	if db.KVExists(scope, args[0]) {
+		React(msg, EmojiCommandOK)
+	} else {
+		React(msg, EmojiCommandWarning)
+	}
+
+	return ""
+}
+
+// ProcessCommandKeys lists keys one page at a time: keys [#here|me] [prefix] [page]
+func ProcessCommandKeys(parts []string, msg slack.MessageInfo) string {
+	page := 1
+
+	// a trailing number selects the page
+	if len(parts) > 1 {
+		if n, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+			page = n
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	scope := model.GlobalScope
+	args := parts[1:]
+	if len(args) > 0 {
+		if s, ok := scopeFromToken(args[0], msg); ok {
+			scope = s
+			args = args[1:]
+		}
+	}
+
+	if len(args) > 1 || page < 1 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	keys, err := db.ListKVKeys(scope, prefix)
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+
+	pages := (len(keys) + KeysPageSize - 1) / KeysPageSize
+	if len(keys) == 0 || page > pages {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
+	start := (page - 1) * KeysPageSize
+	end := start + KeysPageSize
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	return i18n.N(Language(msg), "kv.keys", len(keys), len(keys), page, pages) +
+		"\n`" + strings.Join(keys[start:end], "` `") + "`"
+}
+
+// ProcessCommandHistory lists the stored versions of a key, newest first: history [#here|me] key
+func ProcessCommandHistory(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 2 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	scope, args := parseKVScope(parts, 1, msg)
+
+	history, err := db.GetKVHistory(scope, args[0])
+	if err != nil || len(history) == 0 {
+		React(msg, EmojiCommandWarning)
+		return ""
+	}
+
+	lang := Language(msg)
+	ret := i18n.T(lang, "kv.history", args[0])
+
+	for i := len(history) - 1; i >= 0; i-- {
+		v := history[i]
+		author := "?"
+		if v.Author != "" {
+			author = "<@" + v.Author + ">"
+		}
+		ret += "\n" + i18n.T(lang, "kv.version", v.Version, v.Value, author, v.Timestamp)
+	}
+
+	return ret
+}
+
+// ProcessCommandRollback restores an earlier version of a key: rollback [#here|me] key version
+func ProcessCommandRollback(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 3 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	version, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	scope, args := parseKVScope(parts[:len(parts)-1], 1, msg)
+
+	err = db.RollbackKVValue(scope, args[0], version, msg.UserID)
+	if err == nil {
+		React(msg, EmojiCommandOK)
+	} else {
+		React(msg, EmojiCommandWarning)
+	}
+
+	return ""
+}
@@ -0,0 +1,145 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// pairsMode removes the --fresh or --rotate flag from randompairs parameters and returns the matching draw mode
+func pairsMode(parts []string) ([]string, string) {
+	var ret []string
+	mode := ""
+
+	for _, p := range parts {
+		switch strings.ToLower(p) {
+		case "--fresh":
+			mode = model.DrawModeFresh
+		case "--rotate":
+			mode = model.DrawModeRotate
+		default:
+			ret = append(ret, p)
+		}
+	}
+
+	return ret, mode
+}
+
+// drawPairs draws and records pairs of members in mode, adding them to the pairing history of group unless it is empty
+func drawPairs(group string, mode string, members []string, msg slack.MessageInfo) (model.Draw, error) {
+	draw := model.Draw{Kind: model.DrawPairs, TeamSize: 2, Repeat: true, Members: members, Mode: mode}
+
+	if group == "" {
+		if mode != "" {
+			return draw, errors.New("--fresh and --rotate need a group")
+		}
+		return recordDraw(draw, msg)
+	}
+
+	recorded := false
+	draw, err := db.DrawPairRound(group, func(history model.PairHistory) (model.Draw, error) {
+		if mode == model.DrawModeFresh {
+			draw.Avoid = history.PairCounts(model.FreshPairRounds)
+		} else if mode == model.DrawModeRotate {
+			draw.Round = history.Rotation
+		}
+
+		d, err := recordDraw(draw, msg)
+		recorded = err == nil
+		return d, err
+	})
+	if err != nil && recorded {
+		// the pairs were drawn, they just aren't in the history
+		fmt.Printf("Recording pairs of %s, %s\n", group, err)
+		return draw, nil
+	}
+
+	return draw, err
+}
+
+// ProcessCommandPairs shows how often members of a group were paired: pairs history devs
+func ProcessCommandPairs(parts []string, msg slack.MessageInfo) string {
+	if len(parts) != 3 || strings.ToLower(parts[1]) != "history" {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	lang := Language(msg)
+
+	history, err := db.GetPairHistory(parts[2])
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+	if len(history.Rounds) == 0 {
+		return i18n.T(lang, "pairs.none", parts[2])
+	}
+
+	return i18n.N(lang, "pairs.history", len(history.Rounds), parts[2], len(history.Rounds)) + formatPairMatrix(history)
+}
+
+// formatPairMatrix returns a numbered list of the members in history and a matrix of how often each two were paired
+func formatPairMatrix(history model.PairHistory) string {
+	members := history.Members()
+	counts := history.PairCounts(0)
+
+	ret := ""
+	for i, m := range members {
+		ret += "\n" + strconv.Itoa(i+1) + ". " + m
+	}
+
+	width := len(strconv.Itoa(len(members)))
+	for _, c := range counts {
+		if w := len(strconv.Itoa(c)); w > width {
+			width = w
+		}
+	}
+	cell := func(s string) string {
+		return fmt.Sprintf("%*s ", width, s)
+	}
+
+	ret += "\n```\n" + cell("")
+	for i := range members {
+		ret += cell(strconv.Itoa(i + 1))
+	}
+
+	for i, a := range members {
+		ret += "\n" + cell(strconv.Itoa(i+1))
+		for _, b := range members {
+			if a == b {
+				ret += cell("-")
+			} else {
+				ret += cell(strconv.Itoa(model.PairCount(counts, a, b)))
+			}
+		}
+	}
+
+	return ret + "\n```"
+}
@@ -0,0 +1,181 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"strings"
+
+	"github.com/tadej/hinko/slack"
+)
+
+// Output is the structured result of a command. Members and Teams are passed on to the next command in a pipeline
+type Output struct {
+	Text    string
+	Members []string
+	Teams   [][]string
+}
+
+// ProcessPipeableCommand - signature of commands whose structured output can be piped into another command
+type ProcessPipeableCommand func([]string, slack.MessageInfo) Output
+
+// PipeableCommands - commands that return structured output, used in place of AcceptedCommands when available
+var PipeableCommands = map[string]ProcessPipeableCommand{
+	"group":       PipeGroup,
+	"randompairs": RandomPairs,
+	"randomteams": RandomTeams,
+}
+
+// PipeGroup returns the members of a group for `group X list` and runs any other group subcommand as usual
+func PipeGroup(parts []string, msg slack.MessageInfo) Output {
+	if len(parts) >= 3 && strings.ToLower(parts[2]) == "list" {
+		return GroupList(parts, msg)
+	}
+	return Output{Text: ProcessCommandGroup(parts, msg)}
+}
+
+// empty reports whether a command produced nothing, which means it failed or only reacted
+func (o Output) empty() bool {
+	return o.Text == "" && len(o.Members) == 0 && len(o.Teams) == 0
+}
+
+// args returns the values that are appended to the next command's parameters in a pipeline
+func (o Output) args() []string {
+	if len(o.Members) > 0 {
+		return o.Members
+	}
+
+	if len(o.Teams) > 0 {
+		var ret []string
+		for _, team := range o.Teams {
+			ret = append(ret, team...)
+		}
+		return ret
+	}
+
+	return strings.Fields(o.Text)
+}
+
+// pipelineEscapes are written as plain text inside a command instead of starting a new stage or a redirection
+var pipelineEscapes = []string{`\|`, `\>`, `\&gt;`}
+
+// parsePipeline splits a message into pipeline stages on "|" and an optional trailing "> [scope] key" redirection.
+// Slack links and mentions (<...>) are left intact, and Slack's escaped "&gt;" counts as ">".
+// "\|" and "\>" are kept in the command as a plain "|" and ">"
+func parsePipeline(message string) ([]string, string) {
+	var stages []string
+	var stage strings.Builder
+	depth := 0
+
+next:
+	for i := 0; i < len(message); i++ {
+		for _, escape := range pipelineEscapes {
+			if strings.HasPrefix(message[i:], escape) {
+				stage.WriteString(escape[1:])
+				i += len(escape) - 1
+				continue next
+			}
+		}
+
+		switch {
+		case message[i] == '<':
+			depth++
+		case message[i] == '>' && depth > 0:
+			depth--
+		case message[i] == '|' && depth == 0:
+			stages = append(stages, stage.String())
+			stage.Reset()
+			continue
+		case message[i] == '>' && depth == 0:
+			return append(stages, stage.String()), strings.TrimSpace(message[i+1:])
+		case strings.HasPrefix(message[i:], "&gt;") && depth == 0:
+			return append(stages, stage.String()), strings.TrimSpace(message[i+len("&gt;"):])
+		}
+		stage.WriteByte(message[i])
+	}
+
+	return append(stages, stage.String()), ""
+}
+
+// runCommand runs a single command, returning false if the command doesn't exist
+func runCommand(parts []string, msg slack.MessageInfo) (Output, bool) {
+	name := strings.ToLower(parts[0])
+
+	if fn := PipeableCommands[name]; fn != nil {
+		return fn(parts, msg), true
+	}
+
+	if fn := GetCommand(name); fn != nil {
+		return Output{Text: fn(parts, msg)}, true
+	}
+
+	return Output{}, false
+}
+
+// executePipeline runs a single command pipeline (cmd1 | cmd2 > key) and returns the response text
+func executePipeline(message string, msg slack.MessageInfo) string {
+	stages, redirect := parsePipeline(message)
+
+	var out Output
+
+	for i, stage := range stages {
+		parts := strings.Split(strings.TrimSpace(stage), " ")
+		if i > 0 {
+			// the previous command failed and has already reacted
+			if out.empty() {
+				return ""
+			}
+			parts = append(parts, out.args()...)
+		}
+
+		var ok bool
+		out, ok = runCommand(parts, msg)
+		if !ok {
+			React(msg, EmojiCommandNotFound)
+			return ""
+		}
+	}
+
+	if redirect != "" {
+		if out.empty() {
+			return ""
+		}
+
+		scope, target := parseKVScope(strings.Fields(redirect), 0, msg)
+		if len(target) != 1 {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+
+		value := strings.TrimSpace(out.Text)
+		err := db.SetKVValue(scope, target[0], value, msg.UserID)
+		auditWrite(msg, "put "+redirect+" "+value, err)
+		if err != nil {
+			React(msg, EmojiCommandError)
+		} else {
+			React(msg, EmojiCommandOK)
+		}
+		return ""
+	}
+
+	return out.Text
+}
@@ -0,0 +1,113 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// balancedFlag removes --balanced from randomteams parameters and returns whether it was there
+func balancedFlag(parts []string) ([]string, bool) {
+	var ret []string
+	balanced := false
+
+	for _, p := range parts {
+		if strings.ToLower(p) == "--balanced" {
+			balanced = true
+		} else {
+			ret = append(ret, p)
+		}
+	}
+
+	return ret, balanced
+}
+
+// formatStrengths returns a line with the expected strength of each team of a balanced draw, in the order they are listed
+func formatStrengths(draw model.Draw, lang string) string {
+	if draw.Mode != model.DrawModeBalanced {
+		return ""
+	}
+
+	var strengths []string
+	for _, team := range draw.Teams {
+		strengths = append(strengths, strconv.Itoa(int(math.Round(model.TeamStrength(team, draw.Ratings)))))
+	}
+
+	return "\n" + i18n.T(lang, "teams.strength", strings.Join(strengths, " · "))
+}
+
+// ProcessCommandRating shows or sets the rating balanced teams use: rating @ana, rating @ana 1600, rating @ana reset
+func ProcessCommandRating(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 2 || len(parts) > 3 {
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	if len(parts) == 3 {
+		return Audited(ProcessCommandRatingSet)(parts, msg)
+	}
+
+	ratings, err := db.GetRatings(parts[1:2])
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+
+	lang := Language(msg)
+	ret := i18n.T(lang, "rating.current", parts[1], int(math.Round(ratings[parts[1]])))
+	if _, ok := db.GetManualRating(parts[1]); ok {
+		ret += " " + i18n.T(lang, "rating.manual")
+	}
+	return ret
+}
+
+// ProcessCommandRatingSet sets or removes a manual rating
+func ProcessCommandRatingSet(parts []string, msg slack.MessageInfo) string {
+	var err error
+
+	if strings.ToLower(parts[2]) == "reset" {
+		err = db.DeleteRating(parts[1])
+	} else {
+		var rating int
+		rating, err = strconv.Atoi(parts[2])
+		if err != nil || rating < 0 {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+		err = db.SetRating(parts[1], rating)
+	}
+
+	if err != nil {
+		React(msg, EmojiCommandError)
+		return ""
+	}
+
+	React(msg, EmojiCommandOK)
+	return ""
+}
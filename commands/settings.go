@@ -0,0 +1,151 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tadej/hinko/i18n"
+	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/slack"
+)
+
+// withDefaultTeams fills in the channel's default team size and group for randomteams: randomteams, randomteams 3, randomteams foosball
+func withDefaultTeams(parts []string, msg slack.MessageInfo) []string {
+	if len(parts) >= 3 {
+		return parts
+	}
+
+	settings := db.GetChannelSettings(msg.Channel)
+	teamSize := ""
+	if settings.TeamSize > 0 {
+		teamSize = strconv.Itoa(settings.TeamSize)
+	}
+
+	switch {
+	case len(parts) == 1 && teamSize != "" && settings.DefaultGroup != "":
+		return []string{parts[0], teamSize, settings.DefaultGroup}
+	case len(parts) == 2 && isNumber(parts[1]) && settings.DefaultGroup != "":
+		return []string{parts[0], parts[1], settings.DefaultGroup}
+	case len(parts) == 2 && !isNumber(parts[1]) && teamSize != "":
+		return []string{parts[0], teamSize, parts[1]}
+	}
+
+	return parts
+}
+
+// withDefaultMatchup inserts the channel's default matchup into score commands without teams: score get, score add 10:8 [*].
+// score reset always needs the teams, and score add 1:2 10:8 already has them even though they look like a score
+func withDefaultMatchup(parts []string, msg slack.MessageInfo) []string {
+	if len(parts) < 2 {
+		return parts
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "get":
+		if len(parts) != 2 {
+			return parts
+		}
+	case "add":
+		args := parts[2:]
+		if len(args) == 2 && args[1] == "*" {
+			args = args[:1]
+		}
+		if len(args) != 1 {
+			return parts
+		}
+		if _, _, err := scoresFromString(args[0]); err != nil {
+			return parts
+		}
+	default:
+		return parts
+	}
+
+	matchup := db.GetChannelSettings(msg.Channel).ScoreMatchup
+	if matchup == "" {
+		return parts
+	}
+
+	return append([]string{parts[0], parts[1], matchup}, parts[2:]...)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// ProcessCommandSettings shows or changes the settings of the channel: settings, settings set teamsize 2, settings unset group
+func ProcessCommandSettings(parts []string, msg slack.MessageInfo) string {
+	if len(parts) < 2 {
+		lang := Language(msg)
+		settings := db.GetChannelSettings(msg.Channel)
+
+		ret := i18n.T(lang, "settings.title")
+		for _, name := range model.SettingNames {
+			value := settings.Get(name)
+			if value == "" {
+				value = "-"
+			}
+			ret += "\n" + i18n.T(lang, "settings.entry", name, value)
+		}
+		return ret
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "set":
+		if len(parts) != 4 {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+	case "unset":
+		if len(parts) != 3 {
+			React(msg, EmojiParametersWrong)
+			return ""
+		}
+	default:
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	return Audited(ProcessCommandSettingsSet)(parts, msg)
+}
+
+// ProcessCommandSettingsSet stores or clears a channel setting
+func ProcessCommandSettingsSet(parts []string, msg slack.MessageInfo) string {
+	value := ""
+	if len(parts) > 3 {
+		value = parts[3]
+	}
+
+	err := db.SetChannelSetting(msg.Channel, strings.ToLower(parts[2]), value)
+	if err != nil {
+		fmt.Println(err)
+		React(msg, EmojiParametersWrong)
+		return ""
+	}
+
+	React(msg, EmojiCommandOK)
+	return ""
+}
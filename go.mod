@@ -1,15 +1,20 @@
 module github.com/tadej/hinko
 
+go 1.21
+
+require (
+	github.com/nlopes/slack v0.5.0
+	github.com/syndtr/goleveldb v0.0.0-20181128100959-b001fa50d6b2
+	github.com/tompng/go-ascii-canvas v0.0.0-20160723024213-0d5ad7facfd1
+	go.etcd.io/bbolt v1.3.10
+)
+
 require (
 	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
 	github.com/gorilla/websocket v1.4.0 // indirect
-	github.com/lusis/go-slackbot v0.0.0-20180109053408-401027ccfef5 // indirect
 	github.com/lusis/slack-test v0.0.0-20180109053238-3c758769bfa6 // indirect
-	github.com/nlopes/slack v0.5.0
 	github.com/onsi/ginkgo v1.7.0 // indirect
 	github.com/onsi/gomega v1.4.3 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
-	github.com/stretchr/testify v1.3.0 // indirect
-	github.com/syndtr/goleveldb v0.0.0-20181128100959-b001fa50d6b2
-	github.com/tompng/go-ascii-canvas v0.0.0-20160723024213-0d5ad7facfd1
+	golang.org/x/sys v0.4.0 // indirect
 )
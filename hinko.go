@@ -29,22 +29,78 @@ import (
 	_ "image/png"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/tadej/hinko/commands"
+	"github.com/tadej/hinko/i18n"
 	"github.com/tadej/hinko/model"
+	"github.com/tadej/hinko/plugins"
 	"github.com/tadej/hinko/slack"
 )
 
 func main() {
+	initBackupConfig()
+
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	fmt.Println("Hinko (c) Tadej Gregorcic")
 	token := os.Getenv("SLACK_TOKEN")
 	slack.Init(token)
 	dbPath := os.Getenv("DATABASE_PATH")
+	dbDriver := os.Getenv("DATABASE_DRIVER")
 	fmt.Println("Opening database at " + dbPath)
-	model.OpenDatabase(dbPath)
-	initInterruptHandler()
+	db, err := model.OpenDatabase(dbDriver, dbPath)
+	if err != nil {
+		fmt.Println("Opening database failed: " + err.Error())
+		os.Exit(1)
+	}
+	commands.UseDatabase(db)
+	if days, err := strconv.Atoi(os.Getenv("AUDIT_RETENTION_DAYS")); err == nil {
+		model.AuditRetention = time.Duration(days) * 24 * time.Hour
+	}
+	if days, err := strconv.Atoi(os.Getenv("DRAW_RETENTION_DAYS")); err == nil {
+		model.DrawRetention = time.Duration(days) * 24 * time.Hour
+	}
+	if lang := os.Getenv("DEFAULT_LANGUAGE"); i18n.Supported(lang) {
+		i18n.DefaultLanguage = lang
+	}
+	commands.FactoidTrigger = os.Getenv("FACTOID_TRIGGER") == "true"
+	if admins := os.Getenv("ADMIN_USERS"); admins != "" {
+		commands.AdminUsers = strings.Split(admins, ",")
+	}
+	if emojis := os.Getenv("VACATION_STATUS_EMOJIS"); emojis != "" {
+		commands.VacationStatusEmojis = strings.Split(emojis, ",")
+	}
+	if cooldown, err := time.ParseDuration(os.Getenv("KARMA_COOLDOWN")); err == nil {
+		commands.KarmaCooldown = cooldown
+	}
+	pluginsPath := os.Getenv("PLUGINS_PATH")
+	if pluginsPath == "" {
+		pluginsPath = "plugins"
+	}
+	fmt.Println("Loading plugins from " + pluginsPath)
+	plugins.Init(pluginsPath)
+	go db.ExpireKVValuesLoop(time.Minute)
+	if model.BackupDir != "" {
+		interval, err := time.ParseDuration(os.Getenv("BACKUP_INTERVAL"))
+		if err != nil {
+			interval = time.Hour
+		}
+		fmt.Println("Writing snapshots to " + model.BackupDir + " every " + interval.String())
+		go db.SnapshotLoop(interval)
+	}
+	syncInterval, err := time.ParseDuration(os.Getenv("SLACK_GROUP_SYNC_INTERVAL"))
+	if err != nil {
+		syncInterval = 15 * time.Minute
+	}
+	slack.SubteamChangedHandler = commands.SyncSlackGroup
+	go commands.SyncSlackGroupsLoop(syncInterval)
+	initInterruptHandler(db)
 
 	fmt.Println("Starting Slack API listener")
 
@@ -61,16 +117,27 @@ func main() {
 		}
 	}
 
-	defer model.CloseDatabase()
+	defer db.Close()
+}
+
+// initBackupConfig reads the snapshot settings, which are shared by the bot and the restore command
+func initBackupConfig() {
+	model.BackupDir = os.Getenv("BACKUP_PATH")
+	if n, err := strconv.Atoi(os.Getenv("BACKUP_KEEP_HOURLY")); err == nil {
+		model.BackupKeepHourly = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("BACKUP_KEEP_DAILY")); err == nil {
+		model.BackupKeepDaily = n
+	}
 }
 
-func initInterruptHandler() {
+func initInterruptHandler(db *model.DB) {
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		fmt.Println("\rctrl+c pressed in terminal")
-		model.CloseDatabase()
+		db.Close()
 		os.Exit(0)
 	}()
 }
@@ -86,23 +153,20 @@ func respond(msg slack.MessageInfo) {
 
 	if msg.IM || mentionedBot {
 		response = processMessage(text, msg)
-		if response != "" {
-			slack.SendMessage(msg.Channel, response)
-		}
+	} else {
+		response = commands.ProcessFactoidTrigger(text, msg)
 	}
-}
 
-func processMessage(message string, msg slack.MessageInfo) string {
-	parts := strings.Split(message, " ")
-	if len(parts) > 0 {
-		fn := commands.AcceptedCommands[strings.ToLower(parts[0])]
-		if fn != nil {
-			return fn(parts, msg)
-		}
+	// karma is counted in every message the bot can see
+	if karma := commands.ProcessKarma(text, msg); karma != "" {
+		slack.SendMessage(msg.Channel, karma)
 	}
 
-	// command not supported
-	commands.React(msg, commands.EmojiCommandNotFound)
+	if response != "" {
+		slack.SendMessage(msg.Channel, response)
+	}
+}
 
-	return ""
+func processMessage(message string, msg slack.MessageInfo) string {
+	return commands.Execute(message, msg)
 }
@@ -0,0 +1,103 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package i18n
+
+var english = Catalog{
+	Name:   "English",
+	Plural: englishPlural,
+	Messages: map[string]string{
+		"help.intro":    "Try the following commands:",
+		"help.reserved": "reserved groups: _pairnames_, _teamnames_",
+		"help.more":     "More info:",
+
+		"group.members":  "`%[1]s` members: %[2]s",
+		"teams.team":     "Team %d",
+		"teams.strength": "Expected strength: %s",
+
+		"rating.current": "%[1]s has a rating of %[2]d.",
+		"rating.manual":  "(set manually)",
+
+		"groups.none":        "No groups yet.",
+		"groups.title":       "Groups:",
+		"groups.entry.one":   "`%[1]s` (%[2]d member) %[3]s",
+		"groups.entry.other": "`%[1]s` (%[2]d members) %[3]s",
+
+		"group.info":           "*%[1]s* %[2]s\nMembers: %[3]s\nOwner: %[4]s\nCreated: %[5]s\nUpdated: %[6]s",
+		"group.count.one":      "%d member",
+		"group.count.other":    "%d members",
+		"group.paused":         "Paused: %s",
+		"group.paused.until":   "%[1]s until %[2]s",
+		"group.rejected":       "Not added, not workspace users: %s",
+		"group.link":           "Linked to %[1]s (%[2]s), last synced %[3]s",
+		"group.link.pull":      "from Slack",
+		"group.link.push":      "two-way",
+		"group.link.conflicts": "Only in hinko: %[1]s. Only in Slack: %[2]s",
+		"group.link.error":     "Last sync failed: %s",
+		"group.link.replace":   "Linking would remove %[1]s from %[2]s, because they aren't in the Slack user group. Add --replace to link anyway, or --push to keep them and add them to Slack",
+
+		"draw.id":       "Draw `%[1]s`, check it with `verify %[1]s`",
+		"draw.verify":   "Draw `%[1]s` (%[2]s) by %[3]s on %[4]s\nSeed: `%[5]s`\nMembers: %[6]s\nTeams: %[7]s\n%[8]s",
+		"draw.match":    "Replaying the seed gives the same teams.",
+		"draw.mismatch": "Replaying the seed gives different teams!",
+
+		"pairs.none":          "No pairings recorded for `%s` yet.",
+		"pairs.history.one":   "Pairings of `%[1]s` in the last round:",
+		"pairs.history.other": "Pairings of `%[1]s` in the last %[2]d rounds:",
+
+		"score.ahead":        "%[1]s currently leads %[2]s: %[3]s.",
+		"score.behind":       "%[1]s currently trails %[2]s: %[3]s.",
+		"score.tied":         "%[1]s and %[2]s are currently tied: %[3]s.",
+		"score.latest.one":   "Here is the latest match result:",
+		"score.latest.other": "Here are the latest %d match results:",
+		"score.playedon":     " on %s",
+
+		"kv.keys.one":   "%[1]d key, page %[2]d/%[3]d:",
+		"kv.keys.other": "%[1]d keys, page %[2]d/%[3]d:",
+
+		"kv.history": "History of `%s`:",
+		"kv.version": "`v%[1]d` %[2]s (%[3]s, %[4]s)",
+
+		"karma.changed": "%[1]s now has %[2]d karma.",
+		"karma.current": "%[1]s has %[2]d karma.",
+		"karma.top":     "Karma leaderboard:",
+
+		"settings.title": "Channel settings:",
+		"settings.entry": "`%[1]s`: %[2]s",
+
+		"backup.list": "Snapshots, newest first:",
+
+		"plugins.none":   "No plugins loaded.",
+		"plugins.loaded": "Loaded plugins:",
+
+		"batch.summary.one":   "%[1]d/%[2]d command succeeded:",
+		"batch.summary.other": "%[1]d/%[2]d commands succeeded:",
+		"batch.stopped.one":   "Stopped after the first error, %d command skipped.",
+		"batch.stopped.other": "Stopped after the first error, %d commands skipped.",
+
+		"audit.title":  "Audit log:",
+		"audit.latest": "Audit log (latest %[1]d of %[2]d):",
+		"audit.entry":  "`%[1]s` %[2]s in %[3]s `%[4]s` %[5]s",
+
+		"language.current": "Your language: %[1]s. Channel language: %[2]s. Available: %[3]s",
+	},
+}
@@ -0,0 +1,132 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package i18n contains the message catalogs and plural rules used for all user-facing text
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is used when no preference is set or a message is missing from a catalog
+var DefaultLanguage = "en"
+
+// PluralRule returns the plural category (one, two, few, other) for n
+type PluralRule func(n int) string
+
+// Catalog holds the messages for one language. Plural messages are stored as key.one, key.two, key.few and key.other
+type Catalog struct {
+	Name     string
+	Plural   PluralRule
+	Messages map[string]string
+}
+
+var catalogs = map[string]Catalog{
+	"en": english,
+	"sl": slovenian,
+}
+
+// Languages returns the codes of all available languages
+func Languages() []string {
+	var ret []string
+	for code := range catalogs {
+		ret = append(ret, code)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Supported reports whether there is a catalog for lang
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Name returns the name of the language in that language
+func Name(lang string) string {
+	if c, ok := catalogs[lang]; ok {
+		return c.Name
+	}
+	return lang
+}
+
+func lookup(lang string, key string) string {
+	if c, ok := catalogs[lang]; ok {
+		if m, ok := c.Messages[key]; ok {
+			return m
+		}
+	}
+	if m, ok := catalogs[DefaultLanguage].Messages[key]; ok {
+		return m
+	}
+	return key
+}
+
+// format applies args to message. Messages without verbs, such as singular forms that leave out the count, are returned as they are
+func format(message string, args []interface{}) string {
+	if len(args) == 0 || !strings.Contains(message, "%") {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// T returns the message for key in lang, formatted with args
+func T(lang string, key string, args ...interface{}) string {
+	return format(lookup(lang, key), args)
+}
+
+// N returns the plural form of the message for key that matches n in lang, formatted with args
+func N(lang string, key string, n int, args ...interface{}) string {
+	c, ok := catalogs[lang]
+	if !ok {
+		lang = DefaultLanguage
+		c = catalogs[lang]
+	}
+
+	category := c.Plural(n)
+	if _, ok := c.Messages[key+"."+category]; !ok {
+		category = "other"
+	}
+
+	return format(lookup(lang, key+"."+category), args)
+}
+
+func englishPlural(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func slovenianPlural(n int) string {
+	switch n % 100 {
+	case 1:
+		return "one"
+	case 2:
+		return "two"
+	case 3, 4:
+		return "few"
+	}
+	return "other"
+}
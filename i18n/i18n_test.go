@@ -0,0 +1,65 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package i18n
+
+import (
+	"testing"
+)
+
+// TestPlural tests plural form selection and fallbacks
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, "Here is the latest match result:"},
+		{"en", 3, "Here are the latest 3 match results:"},
+		{"sl", 1, "Zadnji rezultat:"},
+		{"sl", 2, "Zadnja 2 rezultata:"},
+		{"sl", 4, "Zadnji 4 rezultati:"},
+		{"sl", 5, "Zadnjih 5 rezultatov:"},
+		{"xx", 1, "Here is the latest match result:"},
+	}
+
+	for _, test := range tests {
+		if got := N(test.lang, "score.latest", test.n, test.n); got != test.want {
+			t.Errorf("N(%s, %d) = %q, want %q", test.lang, test.n, got, test.want)
+		}
+	}
+
+	if got := T("sl", "missing.key"); got != "missing.key" {
+		t.Errorf("Expected missing key to be returned as is, got %q", got)
+	}
+}
+
+// TestCatalogsComplete checks that every language translates every English message
+func TestCatalogsComplete(t *testing.T) {
+	for _, lang := range Languages() {
+		for key := range english.Messages {
+			if _, ok := catalogs[lang].Messages[key]; !ok {
+				t.Errorf("%s catalog is missing %s", lang, key)
+			}
+		}
+	}
+}
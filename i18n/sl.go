@@ -0,0 +1,117 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package i18n
+
+var slovenian = Catalog{
+	Name:   "Slovenščina",
+	Plural: slovenianPlural,
+	Messages: map[string]string{
+		"help.intro":    "Poskusi naslednje ukaze:",
+		"help.reserved": "rezervirane skupine: _pairnames_, _teamnames_",
+		"help.more":     "Več informacij:",
+
+		"group.members":  "Člani skupine `%[1]s`: %[2]s",
+		"teams.team":     "Ekipa %d",
+		"teams.strength": "Pričakovana moč: %s",
+
+		"rating.current": "%[1]s ima oceno %[2]d.",
+		"rating.manual":  "(nastavljena ročno)",
+
+		"groups.none":        "Ni še nobene skupine.",
+		"groups.title":       "Skupine:",
+		"groups.entry.one":   "`%[1]s` (%[2]d član) %[3]s",
+		"groups.entry.two":   "`%[1]s` (%[2]d člana) %[3]s",
+		"groups.entry.few":   "`%[1]s` (%[2]d člani) %[3]s",
+		"groups.entry.other": "`%[1]s` (%[2]d članov) %[3]s",
+
+		"group.info":           "*%[1]s* %[2]s\nČlani: %[3]s\nLastnik: %[4]s\nUstvarjena: %[5]s\nSpremenjena: %[6]s",
+		"group.count.one":      "%d član",
+		"group.count.two":      "%d člana",
+		"group.count.few":      "%d člani",
+		"group.count.other":    "%d članov",
+		"group.paused":         "Odsotni: %s",
+		"group.paused.until":   "%[1]s do %[2]s",
+		"group.rejected":       "Niso dodani, ker niso uporabniki: %s",
+		"group.link":           "Povezana z %[1]s (%[2]s), nazadnje usklajena %[3]s",
+		"group.link.pull":      "iz Slacka",
+		"group.link.push":      "dvosmerno",
+		"group.link.conflicts": "Samo v hinku: %[1]s. Samo v Slacku: %[2]s",
+		"group.link.error":     "Zadnja uskladitev ni uspela: %s",
+		"group.link.replace":   "Povezava bi iz skupine %[2]s odstranila %[1]s, ker niso v Slackovi skupini uporabnikov. Dodaj --replace za povezavo kljub temu ali --push, da ostanejo in se dodajo v Slack",
+
+		"draw.id":       "Žreb `%[1]s`, preveri ga z `verify %[1]s`",
+		"draw.verify":   "Žreb `%[1]s` (%[2]s), %[3]s, %[4]s\nSeme: `%[5]s`\nČlani: %[6]s\nEkipe: %[7]s\n%[8]s",
+		"draw.match":    "Ponovitev s tem semenom da enake ekipe.",
+		"draw.mismatch": "Ponovitev s tem semenom da drugačne ekipe!",
+
+		"pairs.none":          "Za `%s` še ni zabeleženih parov.",
+		"pairs.history.one":   "Pari skupine `%[1]s` v zadnjem krogu:",
+		"pairs.history.two":   "Pari skupine `%[1]s` v zadnjih %[2]d krogih:",
+		"pairs.history.few":   "Pari skupine `%[1]s` v zadnjih %[2]d krogih:",
+		"pairs.history.other": "Pari skupine `%[1]s` v zadnjih %[2]d krogih:",
+
+		"score.ahead":        "%[1]s trenutno vodi proti %[2]s: %[3]s.",
+		"score.behind":       "%[1]s trenutno zaostaja za %[2]s: %[3]s.",
+		"score.tied":         "%[1]s in %[2]s sta trenutno izenačena: %[3]s.",
+		"score.latest.one":   "Zadnji rezultat:",
+		"score.latest.two":   "Zadnja %d rezultata:",
+		"score.latest.few":   "Zadnji %d rezultati:",
+		"score.latest.other": "Zadnjih %d rezultatov:",
+		"score.playedon":     " dne %s",
+
+		"kv.keys.one":   "%[1]d ključ, stran %[2]d/%[3]d:",
+		"kv.keys.two":   "%[1]d ključa, stran %[2]d/%[3]d:",
+		"kv.keys.few":   "%[1]d ključi, stran %[2]d/%[3]d:",
+		"kv.keys.other": "%[1]d ključev, stran %[2]d/%[3]d:",
+
+		"kv.history": "Zgodovina `%s`:",
+		"kv.version": "`v%[1]d` %[2]s (%[3]s, %[4]s)",
+
+		"karma.changed": "%[1]s ima zdaj %[2]d karme.",
+		"karma.current": "%[1]s ima %[2]d karme.",
+		"karma.top":     "Lestvica karme:",
+
+		"settings.title": "Nastavitve kanala:",
+		"settings.entry": "`%[1]s`: %[2]s",
+
+		"backup.list": "Posnetki, najnovejši najprej:",
+
+		"plugins.none":   "Ni naloženih vtičnikov.",
+		"plugins.loaded": "Naloženi vtičniki:",
+
+		"batch.summary.one":   "Uspešen %[1]d/%[2]d ukaz:",
+		"batch.summary.two":   "Uspešna %[1]d/%[2]d ukaza:",
+		"batch.summary.few":   "Uspešni %[1]d/%[2]d ukazi:",
+		"batch.summary.other": "Uspešnih %[1]d/%[2]d ukazov:",
+		"batch.stopped.one":   "Ustavljeno ob prvi napaki, preskočen %d ukaz.",
+		"batch.stopped.two":   "Ustavljeno ob prvi napaki, preskočena %d ukaza.",
+		"batch.stopped.few":   "Ustavljeno ob prvi napaki, preskočeni %d ukazi.",
+		"batch.stopped.other": "Ustavljeno ob prvi napaki, preskočenih %d ukazov.",
+
+		"audit.title":  "Revizijska sled:",
+		"audit.latest": "Revizijska sled (zadnjih %[1]d od %[2]d):",
+		"audit.entry":  "`%[1]s` %[2]s v %[3]s `%[4]s` %[5]s",
+
+		"language.current": "Tvoj jezik: %[1]s. Jezik kanala: %[2]s. Na voljo: %[3]s",
+	},
+}
@@ -0,0 +1,120 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// AuditRetention is how long audit records are kept; zero keeps them forever
+var AuditRetention = 90 * 24 * time.Hour
+
+var auditSequence uint64
+
+// AuditRecord describes who ran a mutating command, where, and how it ended
+type AuditRecord struct {
+	Timestamp time.Time
+	UserID    string
+	Channel   string
+	Command   string
+	Result    string
+}
+
+const auditPrefix = "[audit::"
+
+func getAuditTag(t time.Time) string {
+	seq := atomic.AddUint64(&auditSequence, 1)
+	return fmt.Sprintf("%s%s:%06d]", auditPrefix, t.UTC().Format("20060102T150405.000000000"), seq%1000000)
+}
+
+// AddAuditRecord stores record and removes records older than AuditRetention
+func (db *DB) AddAuditRecord(record AuditRecord) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	js, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	err = db.SetDBValue(getAuditTag(record.Timestamp), string(js))
+	if err != nil {
+		return err
+	}
+
+	return db.pruneAuditRecords(time.Now().Add(-AuditRetention))
+}
+
+// pruneAuditRecords deletes audit records older than cutoff. Keys are time-ordered, so it stops at the first newer record
+func (db *DB) pruneAuditRecords(cutoff time.Time) error {
+	if AuditRetention <= 0 {
+		return nil
+	}
+
+	var expired []string
+	cutoffTag := auditPrefix + cutoff.UTC().Format("20060102T150405.000000000")
+
+	err := db.IterateDBPrefix(auditPrefix, func(key string, value string) bool {
+		if key >= cutoffTag {
+			return false
+		}
+		expired = append(expired, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range expired {
+		if err = db.DeleteDBValue(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetAuditRecords returns audit records since the given time, oldest first, that match filter
+func (db *DB) GetAuditRecords(since time.Time, filter func(AuditRecord) bool) ([]AuditRecord, error) {
+	var records []AuditRecord
+	sinceTag := auditPrefix + since.UTC().Format("20060102T150405.000000000")
+
+	err := db.IterateDBPrefix(auditPrefix, func(key string, value string) bool {
+		if key < sinceTag {
+			return true
+		}
+
+		var record AuditRecord
+		if json.Unmarshal([]byte(value), &record) == nil && (filter == nil || filter(record)) {
+			records = append(records, record)
+		}
+		return true
+	})
+
+	return records, err
+}
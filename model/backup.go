@@ -0,0 +1,239 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupDir is where snapshots are written; snapshots are disabled if it's empty
+var BackupDir = ""
+
+// BackupKeepHourly is the number of most recent hours for which one snapshot is kept
+var BackupKeepHourly = 24
+
+// BackupKeepDaily is the number of most recent days for which one snapshot is kept
+var BackupKeepDaily = 7
+
+const snapshotPrefix = "hinko-"
+const snapshotSuffix = ".json"
+const snapshotTimeFormat = "20060102T150405.000000000"
+
+// legacySnapshotTimeFormat is used in the names of snapshots written before they had sub-second precision
+const legacySnapshotTimeFormat = "20060102T150405"
+
+// Snapshot is a backup file in BackupDir
+type Snapshot struct {
+	Name string
+	Path string
+	Time time.Time
+}
+
+// CreateSnapshot writes a dump of the database into BackupDir and returns the snapshot
+func (db *DB) CreateSnapshot() (Snapshot, error) {
+	if BackupDir == "" {
+		return Snapshot{}, fmt.Errorf("no backup directory configured")
+	}
+
+	err := os.MkdirAll(BackupDir, 0700)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	dump, err := db.ExportDatabase()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	js, err := json.Marshal(dump)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	now := time.Now()
+	name := snapshotPrefix + now.UTC().Format(snapshotTimeFormat) + snapshotSuffix
+	path := filepath.Join(BackupDir, name)
+
+	// write to a temporary file first so a crash never leaves a half-written snapshot
+	if err = writeFileAtomic(path, js); err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{Name: name, Path: path, Time: now}, nil
+}
+
+// writeFileAtomic writes data to a new temporary file next to path, syncs it and renames it to path
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// ListSnapshots returns the snapshots in BackupDir, newest first
+func ListSnapshots() ([]Snapshot, error) {
+	files, err := ioutil.ReadDir(BackupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []Snapshot
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix)
+		t, err := time.Parse(snapshotTimeFormat, stamp)
+		if err != nil {
+			t, err = time.Parse(legacySnapshotTimeFormat, stamp)
+		}
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Name: name, Path: filepath.Join(BackupDir, name), Time: t})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.After(snapshots[j].Time) })
+
+	return snapshots, nil
+}
+
+// snapshotsToKeep returns the newest snapshot of each of the keepHourly most recent hours and keepDaily most recent days.
+// snapshots must be sorted newest first
+func snapshotsToKeep(snapshots []Snapshot, keepHourly int, keepDaily int) map[string]bool {
+	keep := make(map[string]bool)
+	hours := make(map[string]bool)
+	days := make(map[string]bool)
+
+	for _, s := range snapshots {
+		hour := s.Time.UTC().Format("2006010215")
+		day := s.Time.UTC().Format("20060102")
+
+		if !hours[hour] && len(hours) < keepHourly {
+			hours[hour] = true
+			keep[s.Name] = true
+		}
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[s.Name] = true
+		}
+	}
+
+	return keep
+}
+
+// PruneSnapshots deletes snapshots that aren't needed for the hourly and daily retention and returns their names
+func PruneSnapshots() ([]string, error) {
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := snapshotsToKeep(snapshots, BackupKeepHourly, BackupKeepDaily)
+
+	var deleted []string
+	for _, s := range snapshots {
+		if keep[s.Name] {
+			continue
+		}
+		if err = os.Remove(s.Path); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, s.Name)
+	}
+
+	return deleted, nil
+}
+
+// SnapshotLoop creates a snapshot and prunes old ones every interval. Run it in a goroutine
+func (db *DB) SnapshotLoop(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		s, err := db.CreateSnapshot()
+		if err == nil {
+			_, err = PruneSnapshots()
+		}
+		if err != nil {
+			fmt.Printf("Snapshot failed: %s\n", err)
+		} else {
+			fmt.Println("Wrote snapshot " + s.Name)
+		}
+	}
+}
+
+// FindSnapshot returns the path of a snapshot given its name in BackupDir or a file path
+func FindSnapshot(name string) (string, error) {
+	if _, err := os.Stat(name); err == nil {
+		return name, nil
+	}
+
+	path := filepath.Join(BackupDir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("snapshot %s not found", name)
+	}
+
+	return path, nil
+}
+
+// RestoreSnapshot replaces all data in the database with the snapshot at path
+func (db *DB) RestoreSnapshot(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var dump Dump
+	err = json.Unmarshal(data, &dump)
+	if err != nil {
+		return err
+	}
+
+	return db.ImportDatabase(dump, true)
+}
@@ -0,0 +1,101 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KarmaEntry is the karma of one user or thing
+type KarmaEntry struct {
+	Name  string
+	Karma int
+}
+
+// IncrKVValue atomically adds delta to the integer value of a user key and returns the new value. Missing keys count as 0
+func (db *DB) IncrKVValue(scope KVScope, key string, delta int, author string) (int, error) {
+	defer db.lockKey(getKVTag(scope, key))()
+
+	n := 0
+	value, err := db.GetKVValue(scope, key)
+	if err == nil {
+		n, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return 0, errors.New("value is not a number")
+		}
+	}
+
+	n += delta
+
+	return n, db.writeKVValue(scope, key, strconv.Itoa(n), author, 0)
+}
+
+func getKarmaTag(name string) string {
+	return "[karma::" + name + "]"
+}
+
+// GetKarma returns the karma of name
+func (db *DB) GetKarma(name string) int {
+	value, err := db.GetDBValue(getKarmaTag(name))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// AddKarma atomically adds delta to the karma of name and returns the new karma
+func (db *DB) AddKarma(name string, delta int) (int, error) {
+	defer db.lockKey(getKarmaTag(name))()
+
+	n := db.GetKarma(name) + delta
+	return n, db.SetDBValue(getKarmaTag(name), strconv.Itoa(n))
+}
+
+// GetKarmaTop returns up to limit entries with the highest karma
+func (db *DB) GetKarmaTop(limit int) ([]KarmaEntry, error) {
+	var entries []KarmaEntry
+
+	err := db.IterateDBPrefix("[karma::", func(key string, value string) bool {
+		n, err := strconv.Atoi(value)
+		if err == nil {
+			entries = append(entries, KarmaEntry{Name: strings.TrimSuffix(strings.TrimPrefix(key, "[karma::"), "]"), Karma: n})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Karma > entries[j].Karma })
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
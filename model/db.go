@@ -24,40 +24,129 @@
 package model
 
 import (
+	"errors"
 	"fmt"
+	"os"
+)
+
+// DB reads and writes the model's data through a Store. Every DB has its own key locks,
+// so several of them (e.g. in parallel tests) don't interfere with each other
+type DB struct {
+	store Store
+	locks keyLocks
+}
 
-	"github.com/syndtr/goleveldb/leveldb"
+// Store drivers selectable with OpenDatabase
+const (
+	DriverLevelDB = "leveldb"
+	DriverBolt    = "bolt"
 )
 
-var db *leveldb.DB
+// NewStore opens a store with driver (leveldb if empty) at path
+func NewStore(driver string, path string) (Store, error) {
+	switch driver {
+	case "", DriverLevelDB:
+		return NewLevelDBStore(path)
+	case DriverBolt, "bbolt":
+		return NewBoltStore(path)
+	}
+	return nil, errors.New("unknown database driver " + driver)
+}
 
-// OpenDatabase opens the levelDB at path and saves the connection in db
-func OpenDatabase(path string) error {
-	var err error
-	db, err = leveldb.OpenFile(path, nil)
-	return err
+// NewDB returns a DB that reads and writes through s
+func NewDB(s Store) *DB {
+	return &DB{store: s, locks: keyLocks{locks: make(map[string]*keyLock)}}
 }
 
-// CloseDatabase closes the db
-func CloseDatabase() error {
-	fmt.Println("Closing database.")
-	return db.Close()
+// OpenDatabase opens the database at path with driver and migrates it to the latest schema version
+func OpenDatabase(driver string, path string) (*DB, error) {
+	db, err := OpenStore(driver, path)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, err := db.Migrate(false)
+	for _, r := range reports {
+		fmt.Fprintln(os.Stderr, "Applied migration "+r.String())
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
-// GetDBValue returns value at key
-func GetDBValue(key string) (string, error) {
-	data, err := db.Get([]byte(key), nil)
+// OpenStore opens the database at path with driver, without running migrations
+func OpenStore(driver string, path string) (*DB, error) {
+	s, err := NewStore(driver, path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(data), nil
+	return NewDB(s), nil
+}
+
+// Close closes the store
+func (db *DB) Close() error {
+	fmt.Fprintln(os.Stderr, "Closing database.")
+	return db.store.Close()
+}
+
+// GetDBValue returns value at key
+func (db *DB) GetDBValue(key string) (string, error) {
+	return db.store.Get(key)
 }
 
 // SetDBValue sets value at key
-func SetDBValue(key string, value string) error {
-	err := db.Put([]byte(key), []byte(value), nil)
+func (db *DB) SetDBValue(key string, value string) error {
+	return db.store.Put(key, value)
+}
+
+// DeleteDBValue deletes value at key
+func (db *DB) DeleteDBValue(key string) error {
+	return db.store.Delete(key)
+}
+
+// IterateDBPrefix calls fn for every key starting with prefix, in key order, until fn returns false
+func (db *DB) IterateDBPrefix(prefix string, fn func(key string, value string) bool) error {
+	return db.store.Iterate(prefix, fn)
+}
+
+// WriteDBBatch applies all operations in batch atomically
+func (db *DB) WriteDBBatch(batch *Batch) error {
+	return db.store.Write(batch)
+}
+
+// CopyStore copies every key from src to dst in batches and returns the number of keys copied
+func CopyStore(src Store, dst Store) (int, error) {
+	const batchSize = 1000
+
+	count := 0
+	batch := new(Batch)
+	var err error
+
+	iterErr := src.Iterate("", func(key string, value string) bool {
+		batch.Put(key, value)
+		count++
+		if batch.Len() >= batchSize {
+			if err = dst.Write(batch); err != nil {
+				return false
+			}
+			batch = new(Batch)
+		}
+		return true
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	if iterErr != nil {
+		return 0, iterErr
+	}
+
+	if batch.Len() > 0 {
+		if err = dst.Write(batch); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
 }
@@ -0,0 +1,273 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// seededRand is a deterministic random generator: SHA-256 of the seed and a counter. With a seed from crypto/rand
+// its output is unpredictable, and the same seed always gives the same sequence, so draws can be replayed
+type seededRand struct {
+	seed    []byte
+	counter uint64
+}
+
+// NewSeed returns a new random seed from crypto/rand as 64 hex characters
+func NewSeed() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+func newSeededRand(seed string) (*seededRand, error) {
+	b, err := hex.DecodeString(seed)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid seed")
+	}
+	return &seededRand{seed: b}, nil
+}
+
+func (r *seededRand) uint64() uint64 {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], r.counter)
+	r.counter++
+
+	h := sha256.New()
+	h.Write(r.seed)
+	h.Write(counter[:])
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// intn returns a uniformly distributed number in [0, n)
+func (r *seededRand) intn(n int) int {
+	// reject the values that would make some results more likely than others
+	limit := ^uint64(0) - ^uint64(0)%uint64(n)
+	for {
+		if v := r.uint64(); v < limit {
+			return int(v % uint64(n))
+		}
+	}
+}
+
+// shuffle shuffles vals in place (Fisher-Yates)
+func (r *seededRand) shuffle(vals []string) {
+	for n := len(vals); n > 1; n-- {
+		i := r.intn(n)
+		vals[n-1], vals[i] = vals[i], vals[n-1]
+	}
+}
+
+// shuffle shuffles vals in place with a fresh random seed
+func shuffle(vals []string) {
+	rng, _ := newSeededRand(NewSeed())
+	rng.shuffle(vals)
+}
+
+// Kinds of draws
+const (
+	DrawPairs = "pairs"
+	DrawTeams = "teams"
+)
+
+// Draw is a recorded random draw that can be replayed from its seed and members
+type Draw struct {
+	ID       string
+	Kind     string
+	Seed     string
+	TeamSize int
+	Repeat   bool
+	Members  []string
+	Teams    [][]string
+	// Mode is DrawModeFresh or DrawModeRotate for randompairs --fresh and --rotate, DrawModeBalanced for randomteams --balanced
+	Mode string `json:",omitempty"`
+	// Avoid counts the earlier pairings a fresh draw avoided
+	Avoid map[string]int `json:",omitempty"`
+	// Round is the round of a rotation
+	Round     int `json:",omitempty"`
+	UserID    string
+	Channel   string
+	Timestamp time.Time
+	// Ratings are the player ratings a balanced draw used
+	Ratings map[string]float64 `json:",omitempty"`
+}
+
+func getDrawTag(id string) string {
+	return "[draw::" + id + "]"
+}
+
+// DrawRetention is how long recorded draws are kept for verify; zero keeps them forever
+var DrawRetention = 90 * 24 * time.Hour
+
+// drawTimePrefix starts the keys that list draw IDs in the order they were recorded, so old draws can be pruned
+const drawTimePrefix = "[drawtime::"
+
+func getDrawTimeTag(t time.Time, id string) string {
+	return drawTimePrefix + t.UTC().Format("20060102T150405.000000000") + ":" + id + "]"
+}
+
+// RecordDraw stores draw under a new random ID and returns it with the ID and time filled in
+func (db *DB) RecordDraw(draw Draw) (Draw, error) {
+	if draw.Timestamp.IsZero() {
+		draw.Timestamp = time.Now()
+	}
+
+	b := make([]byte, 4)
+
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return draw, err
+		}
+		draw.ID = hex.EncodeToString(b)
+
+		stored, err := db.storeNewDraw(draw)
+		if err != nil {
+			return draw, err
+		}
+		if stored {
+			break
+		}
+	}
+
+	return draw, db.pruneDraws(time.Now().Add(-DrawRetention))
+}
+
+// storeNewDraw stores draw unless its ID is already taken. The ID is locked between the check and the write
+func (db *DB) storeNewDraw(draw Draw) (bool, error) {
+	defer db.lockKey(getDrawTag(draw.ID))()
+
+	if _, err := db.GetDBValue(getDrawTag(draw.ID)); err != ErrNotFound {
+		return false, err
+	}
+
+	js, err := json.Marshal(draw)
+	if err != nil {
+		return false, err
+	}
+
+	batch := new(Batch)
+	batch.Put(getDrawTag(draw.ID), string(js))
+	batch.Put(getDrawTimeTag(draw.Timestamp, draw.ID), draw.ID)
+	return true, db.WriteDBBatch(batch)
+}
+
+// pruneDraws deletes draws recorded before cutoff. Time keys are ordered, so it stops at the first newer draw
+func (db *DB) pruneDraws(cutoff time.Time) error {
+	if DrawRetention <= 0 {
+		return nil
+	}
+
+	batch := new(Batch)
+	cutoffTag := drawTimePrefix + cutoff.UTC().Format("20060102T150405.000000000")
+
+	err := db.IterateDBPrefix(drawTimePrefix, func(key string, value string) bool {
+		if key >= cutoffTag {
+			return false
+		}
+		batch.Delete(key)
+		batch.Delete(getDrawTag(value))
+		return true
+	})
+	if err != nil || batch.Len() == 0 {
+		return err
+	}
+
+	return db.WriteDBBatch(batch)
+}
+
+// GetDraw returns the recorded draw with id
+func (db *DB) GetDraw(id string) (Draw, error) {
+	var draw Draw
+
+	value, err := db.GetDBValue(getDrawTag(id))
+	if err != nil {
+		return draw, err
+	}
+
+	err = json.Unmarshal([]byte(value), &draw)
+	return draw, err
+}
+
+// Replay draws the teams again from the seed and members of draw
+func (draw Draw) Replay() ([][]string, error) {
+	switch draw.Mode {
+	case DrawModeFresh:
+		return DrawFreshPairs(draw.Members, draw.Seed, draw.Avoid)
+	case DrawModeRotate:
+		return RotatePairs(draw.Members, draw.Round)
+	case DrawModeBalanced:
+		return DrawBalancedTeams(draw.TeamSize, draw.Members, draw.Ratings, draw.Seed)
+	}
+	return DrawTeamList(draw.TeamSize, draw.Members, draw.Repeat, draw.Seed)
+}
+
+// teamNameCounter starts the counter range used to shuffle team names, far from the numbers the draw itself uses
+const teamNameCounter = 1 << 63
+
+// ShuffleTeamNames returns a copy of teamNames shuffled with the seed of draw, so the same draw always gets the same names
+func (draw Draw) ShuffleTeamNames(teamNames []string) []string {
+	names := append([]string(nil), teamNames...)
+
+	rng, err := newSeededRand(draw.Seed)
+	if err != nil {
+		shuffle(names)
+		return names
+	}
+
+	rng.counter = teamNameCounter
+	rng.shuffle(names)
+	return names
+}
+
+// Verify returns true if replaying draw gives the recorded teams
+func (draw Draw) Verify() (bool, error) {
+	teams, err := draw.Replay()
+	if err != nil {
+		return false, err
+	}
+
+	if len(teams) != len(draw.Teams) {
+		return false, nil
+	}
+	for i := range teams {
+		if len(teams[i]) != len(draw.Teams[i]) {
+			return false, nil
+		}
+		for j := range teams[i] {
+			if teams[i][j] != draw.Teams[i][j] {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
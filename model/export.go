@@ -0,0 +1,357 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DumpVersion is the format version written by ExportDatabase
+const DumpVersion = 1
+
+// KVRecord is one user key-value pair in a dump
+type KVRecord struct {
+	Scope   KVScope
+	Key     string
+	Value   string
+	Expires string      `json:",omitempty"`
+	History []KVVersion `json:",omitempty"`
+}
+
+// Dump is a typed copy of all hinko data, used for backups and migrations
+type Dump struct {
+	Version          int
+	Exported         string
+	Groups           map[string][]string
+	GroupDetails     map[string]GroupInfo `json:",omitempty"`
+	Scores           []ScoreInfo
+	KV               []KVRecord
+	Karma            map[string]int
+	UserLanguages    map[string]string
+	ChannelLanguages map[string]string
+	ChannelSettings  map[string]ChannelSettings `json:",omitempty"`
+	Audit            []AuditRecord
+	Draws            []Draw         `json:",omitempty"`
+	PairHistories    []PairHistory  `json:",omitempty"`
+	Ratings          map[string]int `json:",omitempty"`
+}
+
+// parseKVTag splits a [kv::...] DB key into its scope and user key
+func parseKVTag(tag string) (KVScope, string, bool) {
+	if !strings.HasPrefix(tag, "[kv::") {
+		return KVScope{}, "", false
+	}
+
+	end := strings.Index(tag, "]")
+	if end < 0 {
+		return KVScope{}, "", false
+	}
+
+	scope := strings.SplitN(tag[len("[kv::"):end], "::", 2)
+	key := tag[end+1:]
+
+	if len(scope) == 1 && scope[0] == ScopeGlobal {
+		return GlobalScope, key, true
+	}
+	if len(scope) == 2 && (scope[0] == ScopeChannel || scope[0] == ScopeUser) {
+		return KVScope{Kind: scope[0], ID: scope[1]}, key, true
+	}
+
+	return KVScope{}, "", false
+}
+
+func trimTag(key string, prefix string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(key, prefix), "]")
+}
+
+// ExportDatabase returns a typed dump of all groups, scores, KV pairs, karma, language preferences and audit records
+func (db *DB) ExportDatabase() (Dump, error) {
+	dump := Dump{Version: DumpVersion, Exported: time.Now().Format(time.RFC3339),
+		Groups: make(map[string][]string), GroupDetails: make(map[string]GroupInfo), Karma: make(map[string]int),
+		UserLanguages: make(map[string]string), ChannelLanguages: make(map[string]string),
+		ChannelSettings: make(map[string]ChannelSettings), Ratings: make(map[string]int)}
+
+	var err error
+
+	err = db.IterateDBPrefix("", func(key string, value string) bool {
+		switch {
+		case strings.HasPrefix(key, "[group::"):
+			group := parseGroupInfo(trimTag(key, "[group::"), value)
+			dump.Groups[group.Name] = group.Members
+			details := group
+			details.Members = nil
+			dump.GroupDetails[group.Name] = details
+		case strings.HasPrefix(key, "[SCORE]"):
+			scoreInfo, e := jsonToScoreInfo(value)
+			if e == nil {
+				dump.Scores = append(dump.Scores, scoreInfo)
+			}
+		case strings.HasPrefix(key, "[kv::"):
+			scope, k, ok := parseKVTag(key)
+			if ok {
+				dump.KV = append(dump.KV, db.exportKVRecord(scope, k, value))
+			}
+		case strings.HasPrefix(key, "[karma::"):
+			n, e := strconv.Atoi(value)
+			if e == nil {
+				dump.Karma[trimTag(key, "[karma::")] = n
+			}
+		case strings.HasPrefix(key, "[language::user::"):
+			dump.UserLanguages[trimTag(key, "[language::user::")] = value
+		case strings.HasPrefix(key, "[language::channel::"):
+			dump.ChannelLanguages[trimTag(key, "[language::channel::")] = value
+		case strings.HasPrefix(key, "[settings::channel::"):
+			var settings ChannelSettings
+			if json.Unmarshal([]byte(value), &settings) == nil {
+				dump.ChannelSettings[trimTag(key, "[settings::channel::")] = settings
+			}
+		case strings.HasPrefix(key, "[draw::"):
+			var draw Draw
+			if json.Unmarshal([]byte(value), &draw) == nil {
+				dump.Draws = append(dump.Draws, draw)
+			}
+		case strings.HasPrefix(key, "[pairs::"):
+			var history PairHistory
+			if json.Unmarshal([]byte(value), &history) == nil {
+				dump.PairHistories = append(dump.PairHistories, history)
+			}
+		case strings.HasPrefix(key, "[rating::"):
+			n, e := strconv.Atoi(value)
+			if e == nil {
+				dump.Ratings[trimTag(key, "[rating::")] = n
+			}
+		case strings.HasPrefix(key, auditPrefix):
+			var record AuditRecord
+			if json.Unmarshal([]byte(value), &record) == nil {
+				dump.Audit = append(dump.Audit, record)
+			}
+		}
+		return true
+	})
+
+	return dump, err
+}
+
+func (db *DB) exportKVRecord(scope KVScope, key string, value string) KVRecord {
+	record := KVRecord{Scope: scope, Key: key, Value: value}
+	record.Expires, _ = db.GetDBValue(getKVExpiryTag(scope, key))
+	record.History, _ = db.GetKVHistory(scope, key)
+	return record
+}
+
+// Validate checks that a dump can be imported
+func (dump Dump) Validate() error {
+	if dump.Version < 1 || dump.Version > DumpVersion {
+		return fmt.Errorf("unsupported dump version %d", dump.Version)
+	}
+
+	for name, members := range dump.Groups {
+		if name == "" || strings.ContainsAny(name, " ]") {
+			return fmt.Errorf("invalid group name %q", name)
+		}
+		for _, m := range members {
+			if strings.Contains(m, " ") {
+				return fmt.Errorf("invalid member %q in group %s", m, name)
+			}
+		}
+	}
+
+	for _, s := range dump.Scores {
+		if s.Team1 == "" || s.Team2 == "" || strings.Compare(s.Team1, s.Team2) > 0 {
+			return fmt.Errorf("invalid score %s:%s", s.Team1, s.Team2)
+		}
+	}
+
+	for _, r := range dump.KV {
+		switch {
+		case r.Key == "":
+			return errors.New("KV record without a key")
+		case r.Scope.Kind == ScopeGlobal && r.Scope.ID != "":
+			return fmt.Errorf("global KV record %s has a scope ID", r.Key)
+		case (r.Scope.Kind == ScopeChannel || r.Scope.Kind == ScopeUser) && (r.Scope.ID == "" || strings.Contains(r.Scope.ID, "]")):
+			return fmt.Errorf("KV record %s has an invalid scope ID", r.Key)
+		case r.Scope.Kind != ScopeGlobal && r.Scope.Kind != ScopeChannel && r.Scope.Kind != ScopeUser:
+			return fmt.Errorf("KV record %s has an unknown scope %q", r.Key, r.Scope.Kind)
+		}
+		if r.Expires != "" {
+			if _, err := time.Parse(time.RFC3339, r.Expires); err != nil {
+				return fmt.Errorf("KV record %s has an invalid expiry: %s", r.Key, err)
+			}
+		}
+	}
+
+	for _, d := range dump.Draws {
+		if d.ID == "" || strings.Contains(d.ID, "]") {
+			return fmt.Errorf("invalid draw ID %q", d.ID)
+		}
+	}
+
+	for _, h := range dump.PairHistories {
+		if h.Group == "" || strings.ContainsAny(h.Group, " ]") {
+			return fmt.Errorf("invalid pair history group %q", h.Group)
+		}
+	}
+
+	return nil
+}
+
+// auditRecordID identifies an audit record independently of its DB key
+func auditRecordID(record AuditRecord) string {
+	return record.Timestamp.UTC().Format(time.RFC3339Nano) + " " + record.UserID + " " + record.Command
+}
+
+// ImportDatabase validates dump and writes it to the database in one batch.
+// With replace, all existing data is deleted first, otherwise records are merged
+func (db *DB) ImportDatabase(dump Dump, replace bool) error {
+	err := dump.Validate()
+	if err != nil {
+		return err
+	}
+
+	batch := new(Batch)
+
+	if replace {
+		err = db.IterateDBPrefix("", func(key string, value string) bool {
+			if key != schemaVersionTag {
+				batch.Delete(key)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, members := range dump.Groups {
+		// dumps written before group metadata existed only have the members
+		group := dump.GroupDetails[name]
+		group.Name = name
+		group.Members = members
+
+		js, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		batch.Put(getGroupTag(name), string(js))
+	}
+
+	for _, s := range dump.Scores {
+		js, err := scoreInfoToJSON(s)
+		if err != nil {
+			return err
+		}
+		batch.Put(getScoreTag(s.Team1, s.Team2), js)
+	}
+
+	for _, r := range dump.KV {
+		batch.Put(getKVTag(r.Scope, r.Key), r.Value)
+
+		if r.Expires != "" {
+			batch.Put(getKVExpiryTag(r.Scope, r.Key), r.Expires)
+		} else {
+			batch.Delete(getKVExpiryTag(r.Scope, r.Key))
+		}
+
+		if len(r.History) > 0 {
+			js, err := json.Marshal(r.History)
+			if err != nil {
+				return err
+			}
+			batch.Put(getKVHistoryTag(r.Scope, r.Key), string(js))
+		}
+	}
+
+	for name, karma := range dump.Karma {
+		batch.Put(getKarmaTag(name), strconv.Itoa(karma))
+	}
+
+	for userID, lang := range dump.UserLanguages {
+		batch.Put(getUserLanguageTag(userID), lang)
+	}
+
+	for channel, lang := range dump.ChannelLanguages {
+		batch.Put(getChannelLanguageTag(channel), lang)
+	}
+
+	for _, draw := range dump.Draws {
+		js, err := json.Marshal(draw)
+		if err != nil {
+			return err
+		}
+		batch.Put(getDrawTag(draw.ID), string(js))
+		batch.Put(getDrawTimeTag(draw.Timestamp, draw.ID), draw.ID)
+	}
+
+	for _, history := range dump.PairHistories {
+		js, err := json.Marshal(history)
+		if err != nil {
+			return err
+		}
+		batch.Put(getPairHistoryTag(history.Group), string(js))
+	}
+
+	for player, rating := range dump.Ratings {
+		batch.Put(getRatingTag(player), strconv.Itoa(rating))
+	}
+
+	for channel, settings := range dump.ChannelSettings {
+		js, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+		batch.Put(getChannelSettingsTag(channel), string(js))
+	}
+
+	// audit keys get a new sequence number on import, so a merge skips records that are already stored
+	existing := make(map[string]bool)
+	if !replace {
+		records, err := db.GetAuditRecords(time.Time{}, nil)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			existing[auditRecordID(record)] = true
+		}
+	}
+
+	for _, record := range dump.Audit {
+		if existing[auditRecordID(record)] {
+			continue
+		}
+		existing[auditRecordID(record)] = true
+
+		js, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		batch.Put(getAuditTag(record.Timestamp), string(js))
+	}
+
+	return db.WriteDBBatch(batch)
+}
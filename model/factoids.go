@@ -0,0 +1,105 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// VariantsHeader is the first line of a value that holds several variants, one per line after it.
+// Values stored with put are a single line, so they are never mistaken for a variant list
+const VariantsHeader = "[variants]"
+
+// TemplateContext holds the values substituted into factoid templates
+type TemplateContext struct {
+	UserID  string
+	Channel string
+	Now     time.Time
+}
+
+var templateRandomGroup = regexp.MustCompile(`\{random:([^{}\s]+)\}`)
+
+// IsVariantList reports whether value holds variants added with AddKVVariant
+func IsVariantList(value string) bool {
+	return strings.HasPrefix(value, VariantsHeader+"\n")
+}
+
+// GetVariants returns the variants stored in value, or value itself if it holds only one
+func GetVariants(value string) []string {
+	if !IsVariantList(value) {
+		return []string{value}
+	}
+	return strings.Split(strings.TrimPrefix(value, VariantsHeader+"\n"), "\n")
+}
+
+// AddKVVariant adds a variant to a user key. Variants can't contain line breaks. An existing plain value is kept as the first variant
+func (db *DB) AddKVVariant(scope KVScope, key string, variant string, author string) error {
+	defer db.lockKey(getKVTag(scope, key))()
+
+	value, err := db.GetKVValue(scope, key)
+	if err != nil || value == "" {
+		return db.writeKVValue(scope, key, VariantsHeader+"\n"+variant, author, 0)
+	}
+
+	if !IsVariantList(value) {
+		value = VariantsHeader + "\n" + value
+	}
+
+	return db.writeKVValue(scope, key, value+"\n"+variant, author, 0)
+}
+
+// randomIndex returns a random number in [0, n) from the crypto-seeded generator draws use
+func randomIndex(n int) int {
+	rng, _ := newSeededRand(NewSeed())
+	return rng.intn(n)
+}
+
+// PickVariant returns one of the variants stored in value at random
+func PickVariant(value string) string {
+	variants := GetVariants(value)
+	return variants[randomIndex(len(variants))]
+}
+
+// ExpandTemplate replaces {user}, {channel}, {date} and {random:group} in value
+func (db *DB) ExpandTemplate(value string, ctx TemplateContext) string {
+	value = strings.Replace(value, "{user}", "<@"+ctx.UserID+">", -1)
+	value = strings.Replace(value, "{channel}", "<#"+ctx.Channel+">", -1)
+	value = strings.Replace(value, "{date}", ctx.Now.Format("2006-01-02"), -1)
+
+	return templateRandomGroup.ReplaceAllStringFunc(value, func(match string) string {
+		name := templateRandomGroup.FindStringSubmatch(match)[1]
+		members, err := db.ResolveGroup(name)
+		if err != nil || len(members) == 0 {
+			return match
+		}
+		return members[randomIndex(len(members))]
+	})
+}
+
+// RenderFactoid picks a variant of value and expands its template
+func (db *DB) RenderFactoid(value string, ctx TemplateContext) string {
+	return db.ExpandTemplate(PickVariant(value), ctx)
+}
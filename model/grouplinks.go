@@ -0,0 +1,249 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// GroupLink connects a group to a Slack user group
+type GroupLink struct {
+	// SlackGroup is the ID of the Slack user group, e.g. S0614TZR7
+	SlackGroup string
+	// Push makes the sync two-way: changes made in hinko are also written to the Slack user group
+	Push bool
+	// Synced is the time of the last successful sync (RFC3339)
+	Synced string `json:",omitempty"`
+	// Members are the Slack user group members at the last sync
+	Members []string `json:",omitempty"`
+	// Error is the error of the last failed sync
+	Error string `json:",omitempty"`
+}
+
+// Prefixes of Slack mentions recognized by MentionID
+const (
+	UserMentionPrefix    = "<@"
+	SubteamMentionPrefix = "<!subteam^"
+)
+
+// MentionID returns U123 for a user mention such as <@U123> or <@U123|name> with UserMentionPrefix,
+// and S123 for a user group mention such as <!subteam^S123|@team> with SubteamMentionPrefix
+func MentionID(mention string, prefix string) (string, bool) {
+	if !strings.HasPrefix(mention, prefix) || !strings.HasSuffix(mention, ">") {
+		return "", false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(mention, prefix), ">")
+	if i := strings.Index(id, "|"); i >= 0 {
+		id = id[:i]
+	}
+	return id, id != ""
+}
+
+// UserIDs returns the IDs of the members that are user mentions
+func UserIDs(members []string) []string {
+	var ids []string
+	for _, m := range members {
+		if id, ok := MentionID(m, UserMentionPrefix); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// mergeLinkedMembers returns the user IDs a linked group should have after a sync. Without push, the Slack user group wins.
+// With push, a member keeps the state set in hinko if it changed there since the last sync (base), and takes the Slack state otherwise
+func mergeLinkedMembers(local []string, base []string, remote []string, push bool) []string {
+	if !push {
+		return remote
+	}
+
+	var merged []string
+	var all []string
+	for _, list := range [][]string{local, remote} {
+		for _, id := range list {
+			if !Contains(all, id) {
+				all = append(all, id)
+			}
+		}
+	}
+
+	for _, id := range all {
+		inLocal, inBase, inRemote := Contains(local, id), Contains(base, id), Contains(remote, id)
+		if inLocal != inBase && inLocal || inLocal == inBase && inRemote {
+			merged = append(merged, id)
+		}
+	}
+
+	return merged
+}
+
+// LinkGroup links group name to the Slack user group slackGroup. With push, changes made in hinko are written to Slack too
+func (db *DB) LinkGroup(name string, slackGroup string, push bool) error {
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
+	}
+	group.Link = &GroupLink{SlackGroup: slackGroup, Push: push}
+
+	return db.setGroupInfo(group)
+}
+
+// UnlinkGroup removes the Slack user group link of group name, keeping its members
+func (db *DB) UnlinkGroup(name string) error {
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
+	}
+	group.Link = nil
+
+	return db.setGroupInfo(group)
+}
+
+// GetLinkedGroups returns the groups linked to Slack user group slackGroup, or all linked groups if slackGroup is empty
+func (db *DB) GetLinkedGroups(slackGroup string) ([]GroupInfo, error) {
+	groups, err := db.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	var linked []GroupInfo
+	for _, g := range groups {
+		if g.Link != nil && (slackGroup == "" || g.Link.SlackGroup == slackGroup) {
+			linked = append(linked, g)
+		}
+	}
+	return linked, nil
+}
+
+// SyncGroup merges the Slack user group members remote into linked group name. For two-way links, push is called
+// with the merged member IDs when Slack has to be updated. Members that aren't users, such as %group references, are kept.
+// push runs without holding the group's lock, so a slow Slack API doesn't block other changes to the group
+func (db *DB) SyncGroup(name string, remote []string, push func([]string) error) error {
+	merged, pushNeeded, err := db.mergeLinkedGroup(name, remote)
+	if err != nil || !pushNeeded {
+		return err
+	}
+
+	pushErr := push(merged)
+
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
+	}
+	// the group was unlinked or relinked while pushing
+	if group.Link == nil || !group.Link.Push {
+		return pushErr
+	}
+
+	if pushErr != nil {
+		group.Link.Error = pushErr.Error()
+	} else {
+		group.Link.Members = merged
+	}
+
+	if err = db.setGroupInfo(group); err != nil {
+		return err
+	}
+	return pushErr
+}
+
+// mergeLinkedGroup stores the members of linked group name merged with remote, and returns them with whether
+// they have to be pushed to Slack. Until the push succeeds the link remembers remote as the Slack members
+func (db *DB) mergeLinkedGroup(name string, remote []string) ([]string, bool, error) {
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return nil, false, err
+	}
+	if group.Link == nil {
+		return nil, false, ErrNotFound
+	}
+
+	merged := mergeLinkedMembers(UserIDs(group.Members), group.Link.Members, remote, group.Link.Push)
+
+	var members []string
+	for _, m := range group.Members {
+		if _, ok := MentionID(m, UserMentionPrefix); !ok {
+			members = append(members, m)
+		}
+	}
+	for _, id := range merged {
+		members = append(members, "<@"+id+">")
+	}
+	group.Members = members
+
+	group.Link.Error = ""
+	group.Link.Members = remote
+	group.Link.Synced = time.Now().Format(time.RFC3339)
+
+	if err = db.setGroupInfo(group); err != nil {
+		return nil, false, err
+	}
+	return merged, group.Link.Push && !sameMembers(merged, remote), nil
+}
+
+// sameMembers returns true if a and b contain the same members in any order
+func sameMembers(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, m := range a {
+		if !Contains(b, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// LinkConflicts returns the user IDs that are only in the group and only in its Slack user group as of the last sync
+func (group GroupInfo) LinkConflicts() ([]string, []string) {
+	if group.Link == nil || group.Link.Synced == "" {
+		return nil, nil
+	}
+
+	local := UserIDs(group.Members)
+
+	var onlyLocal []string
+	var onlyRemote []string
+	for _, id := range local {
+		if !Contains(group.Link.Members, id) {
+			onlyLocal = append(onlyLocal, id)
+		}
+	}
+	for _, id := range group.Link.Members {
+		if !Contains(local, id) {
+			onlyRemote = append(onlyRemote, id)
+		}
+	}
+
+	return onlyLocal, onlyRemote
+}
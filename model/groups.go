@@ -26,10 +26,11 @@ package model
 import (
 	"encoding/json"
 	"errors"
-	"math/rand"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tadej/hinko/i18n"
 )
 
 // ScoreInfo struct keeps the scores for team1 and team2
@@ -47,7 +48,8 @@ type Score struct {
 	Timestamp string
 }
 
-func contains(s []string, e string) bool {
+// Contains returns true if s contains e
+func Contains(s []string, e string) bool {
 	for _, a := range s {
 		if a == e {
 			return true
@@ -56,103 +58,687 @@ func contains(s []string, e string) bool {
 	return false
 }
 
+// GroupInfo is the stored record of a group
+type GroupInfo struct {
+	Name        string
+	Members     []string
+	Owner       string `json:",omitempty"`
+	Description string `json:",omitempty"`
+	Created     string `json:",omitempty"`
+	Updated     string `json:",omitempty"`
+	// AllowNonUsers marks groups whose members don't have to be workspace users, e.g. team names
+	AllowNonUsers bool `json:",omitempty"`
+	// Link connects the group to a Slack user group
+	Link *GroupLink `json:",omitempty"`
+	// Paused maps paused members to the last day (2006-01-02) of their absence, or to "" until they are resumed
+	Paused map[string]string `json:",omitempty"`
+}
+
+// PauseDateFormat is the format of the dates members are paused until
+const PauseDateFormat = "2006-01-02"
+
+// isPaused returns true if a member paused until the end of day until is still paused at now
+func isPaused(until string, now time.Time) bool {
+	if until == "" {
+		return true
+	}
+
+	day, err := time.ParseInLocation(PauseDateFormat, until, time.Local)
+	if err != nil {
+		return false
+	}
+
+	return now.Before(day.AddDate(0, 0, 1))
+}
+
+// PausedMembers returns the members of group that are currently paused, with the day their absence ends
+func (group GroupInfo) PausedMembers() map[string]string {
+	paused := make(map[string]string)
+	now := time.Now()
+
+	for m, until := range group.Paused {
+		if isPaused(until, now) {
+			paused[m] = until
+		}
+	}
+
+	return paused
+}
+
+func getGroupTag(name string) string {
+	return "[group::" + name + "]"
+}
+
+// parseGroupInfo reads a group record. Groups stored before schema version 1 are a space-joined list of members
+func parseGroupInfo(name string, value string) GroupInfo {
+	var group GroupInfo
+	if json.Unmarshal([]byte(value), &group) == nil && group.Name != "" {
+		return group
+	}
+
+	group = GroupInfo{Name: name}
+	for _, m := range strings.Split(value, " ") {
+		if m != "" {
+			group.Members = append(group.Members, m)
+		}
+	}
+	return group
+}
+
+// GetGroupInfo returns the record of group name
+func (db *DB) GetGroupInfo(name string) (GroupInfo, error) {
+	value, err := db.GetDBValue(getGroupTag(name))
+	if err != nil {
+		return GroupInfo{}, err
+	}
+	return parseGroupInfo(name, value), nil
+}
+
+// setGroupInfo stores group, stamping its creation and update time and dropping pauses that ended
+func (db *DB) setGroupInfo(group GroupInfo) error {
+	if group.Paused != nil {
+		group.Paused = group.PausedMembers()
+		if len(group.Paused) == 0 {
+			group.Paused = nil
+		}
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	if group.Created == "" {
+		group.Created = now
+	}
+	group.Updated = now
+
+	js, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	return db.SetDBValue(getGroupTag(group.Name), string(js))
+}
+
 // GetGroup returns a list of members in group name
-func GetGroup(name string) ([]string, error) {
-	group, err := GetDBValue("[group::" + name + "]")
+func (db *DB) GetGroup(name string) ([]string, error) {
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return group.Members, nil
+}
+
+// GroupRefPrefix marks a member that includes another group, e.g. %frontend
+const GroupRefPrefix = "%"
+
+// ErrGroupCycle is returned when groups include each other
+var ErrGroupCycle = errors.New("groups include each other")
+
+// ResolveGroup returns the active members of group name with included groups expanded recursively and duplicates removed.
+// Members paused in the group or in an included group are skipped
+func (db *DB) ResolveGroup(name string) ([]string, error) {
+	return db.resolveGroup(name, make(map[string]bool))
+}
+
+// ResolveMembers expands group references in members recursively and removes duplicates
+func (db *DB) ResolveMembers(members []string) ([]string, error) {
+	return db.resolveMembers(members, make(map[string]bool))
+}
+
+func (db *DB) resolveGroup(name string, visiting map[string]bool) ([]string, error) {
+	if visiting[name] {
+		return nil, ErrGroupCycle
+	}
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return nil, err
+	}
+
+	visiting[name] = true
+	defer delete(visiting, name)
 
+	members, err := db.resolveMembers(group.Members, visiting)
 	if err != nil {
 		return nil, err
 	}
 
-	members := strings.Split(group, " ")
+	paused := group.PausedMembers()
+	if len(paused) == 0 {
+		return members, nil
+	}
+
+	var active []string
+	for _, m := range members {
+		if _, ok := paused[m]; !ok {
+			active = append(active, m)
+		}
+	}
+
+	return active, nil
+}
+
+func (db *DB) resolveMembers(members []string, visiting map[string]bool) ([]string, error) {
+	var resolved []string
+
+	for _, m := range members {
+		if !strings.HasPrefix(m, GroupRefPrefix) {
+			if !Contains(resolved, m) {
+				resolved = append(resolved, m)
+			}
+			continue
+		}
+
+		sub, err := db.resolveGroup(strings.TrimPrefix(m, GroupRefPrefix), visiting)
+		if err == ErrGroupCycle {
+			return nil, err
+		}
+		// included groups that were deleted are skipped
+		for _, s := range sub {
+			if !Contains(resolved, s) {
+				resolved = append(resolved, s)
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// checkGroupRefs returns an error if members include a group that doesn't exist or that includes group name
+func (db *DB) checkGroupRefs(name string, members []string) error {
+	for _, m := range members {
+		if !strings.HasPrefix(m, GroupRefPrefix) {
+			continue
+		}
+
+		ref := strings.TrimPrefix(m, GroupRefPrefix)
+		if ref == name {
+			return ErrGroupCycle
+		}
+
+		_, err := db.resolveGroup(ref, map[string]bool{name: true})
+		if err == ErrGroupCycle {
+			return err
+		}
+		if err != nil {
+			return errors.New("group " + ref + " doesn't exist")
+		}
+	}
+	return nil
+}
+
+// ResolveGroupExpression returns the members of a group expression like frontend+backend-interns,
+// evaluated left to right as union (+) and difference (-). An existing group with the exact name takes precedence
+func (db *DB) ResolveGroupExpression(expr string) ([]string, error) {
+	expr = strings.TrimPrefix(expr, GroupRefPrefix)
+	if _, err := db.GetGroupInfo(expr); err == nil {
+		return db.ResolveGroup(expr)
+	}
+
+	var resolved []string
+	op := byte('+')
+	start := 0
+
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) && expr[i] != '+' && expr[i] != '-' {
+			continue
+		}
+
+		name := strings.TrimPrefix(expr[start:i], GroupRefPrefix)
+		members, err := db.ResolveGroup(name)
+		if err != nil {
+			return nil, errors.New("group " + name + " doesn't exist")
+		}
 
-	return members, nil
+		if op == '+' {
+			for _, m := range members {
+				if !Contains(resolved, m) {
+					resolved = append(resolved, m)
+				}
+			}
+		} else {
+			var remaining []string
+			for _, m := range resolved {
+				if !Contains(members, m) {
+					remaining = append(remaining, m)
+				}
+			}
+			resolved = remaining
+		}
+
+		if i < len(expr) {
+			op = expr[i]
+		}
+		start = i + 1
+	}
+
+	return resolved, nil
 }
 
-// SetGroup creates a group with members[]
-func SetGroup(name string, members []string) error {
-	err := SetDBValue("[group::"+name+"]", strings.Join(members, " "))
+// groupRefsKey is locked while members that include other groups are stored, so that two concurrent changes
+// can't each pass the cycle check and create a cycle together
+const groupRefsKey = "[group-refs]"
+
+// lockGroupMembers locks group name for a change of its members, and groupRefsKey too if members include other groups
+func (db *DB) lockGroupMembers(name string, members []string) func() {
+	for _, m := range members {
+		if strings.HasPrefix(m, GroupRefPrefix) {
+			return db.lockKeys(getGroupTag(name), groupRefsKey)
+		}
+	}
+	return db.lockKey(getGroupTag(name))
+}
+
+// SetGroup creates a group with members[] owned by author, or replaces the members of an existing one.
+// Empty and repeated members are dropped. Members starting with % include other groups
+func (db *DB) SetGroup(name string, members []string, author string) error {
+	defer db.lockGroupMembers(name, members)()
+
+	if err := db.checkGroupRefs(name, members); err != nil {
+		return err
+	}
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		group = GroupInfo{Name: name, Owner: author}
+	}
+
+	group.Members = nil
+	for _, m := range members {
+		if m != "" && !Contains(group.Members, m) {
+			group.Members = append(group.Members, m)
+		}
+	}
+
+	return db.setGroupInfo(group)
+}
+
+// SetGroupAllowNonUsers sets whether members of group name have to be workspace users
+func (db *DB) SetGroupAllowNonUsers(name string, allow bool) error {
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
 	if err != nil {
 		return err
 	}
-	return nil
+	group.AllowNonUsers = allow
+
+	return db.setGroupInfo(group)
 }
 
-// AddToGroup adds members[] to a group (no duplicates are created)
-func AddToGroup(name string, members []string) error {
-	var str string
+// AddToGroup adds members[] to a group, creating it with owner author if needed (no duplicates are created).
+// Members starting with % include other groups
+func (db *DB) AddToGroup(name string, members []string, author string) error {
+	defer db.lockGroupMembers(name, members)()
+
+	if err := db.checkGroupRefs(name, members); err != nil {
+		return err
+	}
 
-	existingGroup, err := GetGroup(name)
+	group, err := db.GetGroupInfo(name)
 	if err != nil {
-		str = ""
-	} else {
-		str = strings.Join(existingGroup, " ")
-		str = strings.Trim(str, " ")
+		group = GroupInfo{Name: name, Owner: author}
 	}
 
 	for _, m := range members {
-		if !contains(existingGroup, m) {
-			str += " " + m
+		if m != "" && !Contains(group.Members, m) {
+			group.Members = append(group.Members, m)
 		}
 	}
 
-	str = strings.Trim(str, " ")
+	return db.setGroupInfo(group)
+}
+
+// RemoveFromGroup removes members[] if they exist and ends their pauses
+func (db *DB) RemoveFromGroup(name string, members []string) error {
+	defer db.lockKey(getGroupTag(name))()
 
-	err = SetDBValue("[group::"+name+"]", str)
+	group, err := db.GetGroupInfo(name)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	var remaining []string
+	for _, m := range group.Members {
+		if !Contains(members, m) {
+			remaining = append(remaining, m)
+		}
+	}
+	group.Members = remaining
+
+	// a removed member's pause ends, unless they are still in the group through an included group
+	all, err := db.ResolveMembers(remaining)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if !Contains(all, m) {
+			delete(group.Paused, m)
+		}
+	}
+
+	return db.setGroupInfo(group)
 }
 
-// RemoveFromGroup removes members[] if they exist
-func RemoveFromGroup(name string, members []string) error {
-	existingGroup, err := GetGroup(name)
+// PauseMembers pauses members of group name until the end of day until (2006-01-02), or until they are resumed if until is empty.
+// Paused members stay in the group but are skipped in draws
+func (db *DB) PauseMembers(name string, members []string, until string) error {
+	if until != "" {
+		if _, err := time.ParseInLocation(PauseDateFormat, until, time.Local); err != nil {
+			return err
+		}
+		if !isPaused(until, time.Now()) {
+			return errors.New("pause end date " + until + " is in the past")
+		}
+	}
+
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
 	if err != nil {
 		return err
 	}
 
-	str := ""
+	all, err := db.ResolveMembers(group.Members)
+	if err != nil {
+		return err
+	}
 
-	for _, m := range existingGroup {
-		if !contains(members, m) {
-			str += " " + m
+	if group.Paused == nil {
+		group.Paused = make(map[string]string)
+	}
+	for _, m := range members {
+		if !Contains(all, m) {
+			return errors.New(m + " isn't a member of group " + name)
 		}
+		group.Paused[m] = until
+	}
+
+	return db.setGroupInfo(group)
+}
+
+// ResumeMembers makes paused members of group name active again
+func (db *DB) ResumeMembers(name string, members []string) error {
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		delete(group.Paused, m)
+	}
+
+	return db.setGroupInfo(group)
+}
+
+// ListGroups returns the records of all groups ordered by name
+func (db *DB) ListGroups() ([]GroupInfo, error) {
+	var groups []GroupInfo
+
+	err := db.IterateDBPrefix("[group::", func(key string, value string) bool {
+		groups = append(groups, parseGroupInfo(trimTag(key, "[group::"), value))
+		return true
+	})
+
+	return groups, err
+}
+
+// DescribeGroup sets the description of group name
+func (db *DB) DescribeGroup(name string, description string) error {
+	defer db.lockKey(getGroupTag(name))()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
 	}
+	group.Description = description
 
-	str = strings.Trim(str, " ")
+	return db.setGroupInfo(group)
+}
 
-	err = SetDBValue("[group::"+name+"]", str)
+// DeleteGroup deletes group name and, in the same batch, removes it from the groups that include it,
+// clears it as a channel's default group and deletes its pair history
+func (db *DB) DeleteGroup(name string) error {
+	unlock, groups, channels, err := db.lockGroupUsers(name, "")
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	if _, err = db.GetGroupInfo(name); err != nil {
+		return err
+	}
+
+	batch := new(Batch)
+	if err = db.moveGroupUsers(name, "", groups, channels, batch); err != nil {
+		return err
+	}
+	batch.Delete(getGroupTag(name))
+
+	return db.WriteDBBatch(batch)
+}
+
+// lockGroups locks two groups in a fixed order so concurrent renames and copies can't deadlock
+func (db *DB) lockGroups(name1 string, name2 string) func() {
+	return db.lockKeys(getGroupTag(name1), getGroupTag(name2))
+}
+
+// groupUsers returns the other groups that include group name and the settings of the channels that draw from it by default
+func (db *DB) groupUsers(name string) ([]GroupInfo, map[string]ChannelSettings, error) {
+	all, err := db.ListGroups()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var groups []GroupInfo
+	for _, g := range all {
+		if g.Name != name && Contains(g.Members, GroupRefPrefix+name) {
+			groups = append(groups, g)
+		}
+	}
+
+	channels := make(map[string]ChannelSettings)
+	err = db.IterateDBPrefix("[settings::channel::", func(key string, value string) bool {
+		var settings ChannelSettings
+		if json.Unmarshal([]byte(value), &settings) == nil && settings.DefaultGroup == name {
+			channels[trimTag(key, "[settings::channel::")] = settings
+		}
+		return true
+	})
+
+	return groups, channels, err
+}
+
+// groupUserKeys returns the keys that renaming group name to newName, or deleting it if newName is empty, rewrites
+func groupUserKeys(name string, newName string, groups []GroupInfo, channels map[string]ChannelSettings) []string {
+	keys := []string{getGroupTag(name), getPairHistoryTag(name)}
+	if newName != "" {
+		keys = append(keys, getGroupTag(newName), getPairHistoryTag(newName))
+	}
+	for _, g := range groups {
+		keys = append(keys, getGroupTag(g.Name))
+	}
+	for channel := range channels {
+		keys = append(keys, getChannelSettingsTag(channel))
+	}
+	return keys
+}
+
+// lockGroupUsers locks group name, newName and everything that refers to name, and returns the function that
+// releases them with the groups and channel settings that refer to name, read while they are locked
+func (db *DB) lockGroupUsers(name string, newName string) (func(), []GroupInfo, map[string]ChannelSettings, error) {
+	for {
+		groups, channels, err := db.groupUsers(name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		locked := groupUserKeys(name, newName, groups, channels)
+		unlock := db.lockKeys(locked...)
+
+		// something may have started to refer to name before it was locked
+		groups, channels, err = db.groupUsers(name)
+		if err != nil {
+			unlock()
+			return nil, nil, nil, err
+		}
+
+		stillLocked := true
+		for _, key := range groupUserKeys(name, newName, groups, channels) {
+			if !Contains(locked, key) {
+				stillLocked = false
+			}
+		}
+		if stillLocked {
+			return unlock, groups, channels, nil
+		}
+		unlock()
+	}
+}
+
+// moveGroupUsers adds to batch the changes that point the groups and channels that refer to group name,
+// and its pair history, to newName. If newName is empty the references and the history are removed
+func (db *DB) moveGroupUsers(name string, newName string, groups []GroupInfo, channels map[string]ChannelSettings, batch *Batch) error {
+	for _, g := range groups {
+		var members []string
+		for _, m := range g.Members {
+			switch {
+			case m != GroupRefPrefix+name:
+				members = append(members, m)
+			case newName != "":
+				members = append(members, GroupRefPrefix+newName)
+			}
+		}
+		g.Members = members
+
+		js, err := json.Marshal(g)
+		if err != nil {
+			return err
+		}
+		batch.Put(getGroupTag(g.Name), string(js))
+	}
+
+	for channel, settings := range channels {
+		settings.DefaultGroup = newName
+		if settings == (ChannelSettings{}) {
+			batch.Delete(getChannelSettingsTag(channel))
+			continue
+		}
+
+		js, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+		batch.Put(getChannelSettingsTag(channel), string(js))
+	}
+
+	history, err := db.GetDBValue(getPairHistoryTag(name))
+	if err == nil && newName != "" {
+		batch.Put(getPairHistoryTag(newName), history)
+	} else if newName != "" {
+		batch.Delete(getPairHistoryTag(newName))
+	}
+	batch.Delete(getPairHistoryTag(name))
+
 	return nil
 }
 
-func shuffle(vals []string) {
-	r := rand.New(rand.NewSource(time.Now().Unix()))
-	for len(vals) > 0 {
-		n := len(vals)
-		randIndex := r.Intn(n)
-		vals[n-1], vals[randIndex] = vals[randIndex], vals[n-1]
-		vals = vals[:n-1]
+// RenameGroup renames group name to newName and, in the same batch, updates the groups that include it,
+// the channels that draw from it by default and its pair history
+func (db *DB) RenameGroup(name string, newName string) error {
+	if name == newName {
+		return errors.New("group " + name + " already has that name")
 	}
+
+	unlock, groups, channels, err := db.lockGroupUsers(name, newName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
+	}
+	if _, err = db.GetGroupInfo(newName); err == nil {
+		return errors.New("group " + newName + " already exists")
+	}
+
+	batch := new(Batch)
+	if err = db.moveGroupUsers(name, newName, groups, channels, batch); err != nil {
+		return err
+	}
+
+	group.Name = newName
+	group.Updated = time.Now().Format(time.RFC3339)
+	js, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	batch.Delete(getGroupTag(name))
+	batch.Put(getGroupTag(newName), string(js))
+
+	return db.WriteDBBatch(batch)
 }
 
-// GetRandomTeams takes a list of strings and puts them into teams of teamSize
-func GetRandomTeams(teamSize int, members []string, membersCanRepeat bool, teamNames []string, shuffleTeamNames bool) (string, error) {
-	ret := "\n"
+// CopyGroup creates group newName owned by author as a copy of group name, with its members, description, pauses, link and settings
+func (db *DB) CopyGroup(name string, newName string, author string) error {
+	if name == newName {
+		return errors.New("group " + name + " already has that name")
+	}
 
-	if teamSize > len(members)/2 {
-		return "", errors.New("Team size can't be more than half the group size")
+	defer db.lockGroups(name, newName)()
+
+	group, err := db.GetGroupInfo(name)
+	if err != nil {
+		return err
+	}
+	if _, err = db.GetGroupInfo(newName); err == nil {
+		return errors.New("group " + newName + " already exists")
 	}
 
-	shuffle(members)
+	paused := group.Paused
+	group.Paused = make(map[string]string)
+	for m, until := range paused {
+		group.Paused[m] = until
+	}
 
-	if shuffleTeamNames {
-		shuffle(teamNames)
+	group.Name = newName
+	group.Owner = author
+	group.Created = ""
+	return db.setGroupInfo(group)
+}
+
+// GetRandomTeamList shuffles members with a fresh random seed and splits them into teams of teamSize.
+// A single leftover member joins the last team
+func GetRandomTeamList(teamSize int, members []string, membersCanRepeat bool) ([][]string, error) {
+	return DrawTeamList(teamSize, members, membersCanRepeat, NewSeed())
+}
+
+// DrawTeamList shuffles a copy of members with seed and splits them into teams of teamSize.
+// The same seed and members always give the same teams. A single leftover member joins the last team
+func DrawTeamList(teamSize int, members []string, membersCanRepeat bool, seed string) ([][]string, error) {
+	if teamSize < 1 {
+		return nil, errors.New("Team size must be at least 1")
+	}
+
+	if teamSize > len(members)/2 {
+		return nil, errors.New("Team size can't be more than half the group size")
+	}
+
+	rng, err := newSeededRand(seed)
+	if err != nil {
+		return nil, err
 	}
 
+	return drawTeamList(teamSize, members, membersCanRepeat, rng), nil
+}
+
+// drawTeamList shuffles a copy of members with rng and splits them into teams of teamSize
+func drawTeamList(teamSize int, members []string, membersCanRepeat bool, rng *seededRand) [][]string {
+	members = append([]string(nil), members...)
+	rng.shuffle(members)
+
 	d := len(members) % teamSize
 
 	if d != 0 && membersCanRepeat {
@@ -160,42 +746,68 @@ func GetRandomTeams(teamSize int, members []string, membersCanRepeat bool, teamN
 		newMembers := make([]string, len(members)+add)
 		copy(newMembers, members)
 
-		if d != 0 && membersCanRepeat {
-			for i := 0; i < add; i++ {
-				newMembers[len(members)+i] = members[i]
-			}
+		for i := 0; i < add; i++ {
+			newMembers[len(members)+i] = members[i]
 		}
 		members = newMembers
 	}
 
-	// TODO: refactor this a bit. Works but looks messy
+	var teams [][]string
 
-	teamIndex := 0
-	currentTeamSize := 0
+	for i := 0; i < len(members); i += teamSize {
+		end := i + teamSize
+		if end > len(members) {
+			end = len(members)
+		}
+
+		if teamSize > 1 && end-i == 1 && len(teams) > 0 {
+			teams[len(teams)-1] = append(teams[len(teams)-1], members[i])
+		} else {
+			teams = append(teams, append([]string(nil), members[i:end]...))
+		}
+	}
+
+	return teams
+}
+
+// FormatTeams returns a message in lang listing teams, named after teamNames where available
+func FormatTeams(teams [][]string, teamSize int, teamNames []string, shuffleTeamNames bool, lang string) string {
+	ret := "\n"
+
+	if shuffleTeamNames {
+		shuffle(teamNames)
+	}
 
-	for i, member := range members {
-		if i%teamSize == 0 {
-			if i == len(members)-1 {
+	for i, team := range teams {
+		if i < len(teamNames) {
+			ret += "\n" + teamNames[i] + ": "
+		} else {
+			ret += "\n" + i18n.T(lang, "teams.team", i+1) + ": "
+		}
+
+		for j, member := range team {
+			if j >= teamSize {
 				ret += "➕ "
-			} else {
-				teamIndex++
-				currentTeamSize = 0
-				if teamIndex-1 < len(teamNames) {
-					ret += "\n" + teamNames[teamIndex-1] + ": "
-				} else {
-					ret += "\nTeam " + strconv.Itoa(teamIndex) + ": "
-				}
 			}
+			ret += member + " "
 		}
-		ret += member + " "
-		currentTeamSize++
 	}
 
-	if currentTeamSize < teamSize {
+	if len(teams) > 0 && len(teams[len(teams)-1]) < teamSize {
 		ret += "➕❓"
 	}
 
-	return ret, nil
+	return ret
+}
+
+// GetRandomTeams takes a list of strings and puts them into teams of teamSize
+func GetRandomTeams(teamSize int, members []string, membersCanRepeat bool, teamNames []string, shuffleTeamNames bool) (string, error) {
+	teams, err := GetRandomTeamList(teamSize, members, membersCanRepeat)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatTeams(teams, teamSize, teamNames, shuffleTeamNames, i18n.DefaultLanguage), nil
 }
 
 func reverseScores(scores ScoreInfo) ScoreInfo {
@@ -275,7 +887,7 @@ func addScore(currentScores ScoreInfo, score1 int, score2 int) ScoreInfo {
 }
 
 // AddScore adds a score for team1 vs team2
-func AddScore(team1 string, team2 string, score1 int, score2 int) error {
+func (db *DB) AddScore(team1 string, team2 string, score1 int, score2 int) error {
 	var reverse bool
 	team1, team2, reverse = orderTeamNames(team1, team2)
 
@@ -285,7 +897,9 @@ func AddScore(team1 string, team2 string, score1 int, score2 int) error {
 		score2 = tmp
 	}
 
-	scoreInfo, err := GetScores(team1, team2)
+	defer db.lockKey(getScoreTag(team1, team2))()
+
+	scoreInfo, err := db.GetScores(team1, team2)
 
 	if err != nil {
 		scoreInfo.Team1 = team1
@@ -301,7 +915,7 @@ func AddScore(team1 string, team2 string, score1 int, score2 int) error {
 	tg := getScoreTag(team1, team2)
 
 	if err == nil {
-		err = SetDBValue(tg, js)
+		err = db.SetDBValue(tg, js)
 	}
 
 	return err
@@ -311,52 +925,43 @@ func scoreToString(score Score) string {
 	return strconv.Itoa(score.Team1) + ":" + strconv.Itoa(score.Team2)
 }
 
-func getVerbToBe(input string) string {
-	if strings.HasSuffix(strings.ToLower(input), "s") {
-		return "are"
-	}
-	return "is"
-}
-
-// GetScoreMessage returns a formatted string describing the score standing
-func GetScoreMessage(score ScoreInfo) string {
+// GetScoreMessage returns a formatted string in lang describing the score standing
+func GetScoreMessage(score ScoreInfo, lang string) string {
 	var ret string
 	prefix := ""
+	points := scoreToString(score.Points)
 
-	ret += "" + score.Team1 + " "
 	if score.Points.Team1 > score.Points.Team2 {
 		prefix += "*" + score.Team1 + "*  :trophy:\n"
 		prefix += "*" + score.Team2 + "*  \n"
 
-		ret += getVerbToBe(score.Team1) + " currently ahead of "
+		ret += i18n.T(lang, "score.ahead", score.Team1, score.Team2, points)
 	} else if score.Points.Team1 < score.Points.Team2 {
 		prefix += "*" + score.Team2 + "*  :trophy:\n"
 		prefix += "*" + score.Team1 + "*  \n"
 
-		ret += getVerbToBe(score.Team1) + " currently trailing behind "
+		ret += i18n.T(lang, "score.behind", score.Team1, score.Team2, points)
 	} else {
 		prefix += "*" + score.Team1 + "*  :first_place_medal:\n"
 		prefix += "*" + score.Team2 + "*  :first_place_medal:\n"
 
-		ret += getVerbToBe(score.Team1) + " currently tied with "
+		ret += i18n.T(lang, "score.tied", score.Team1, score.Team2, points)
 	}
-	ret += "" + score.Team2 + ""
-	ret += ": " + scoreToString(score.Points) + "."
 
 	if len(score.Scores) > 0 {
-		ret += "\n\nHere are the latest match results:"
-
 		gamesPlayed := len(score.Scores)
 		if gamesPlayed > 10 {
 			gamesPlayed = 10
 		}
 
+		ret += "\n\n" + i18n.N(lang, "score.latest", gamesPlayed, gamesPlayed)
+
 		for i := 1; i <= gamesPlayed; i++ {
 			timestr := ""
 			j := len(score.Scores) - i
 			t, err := time.Parse(time.RFC1123, score.Scores[j].Timestamp)
 			if err == nil {
-				timestr = " on " + t.Format(time.RFC1123)
+				timestr = i18n.T(lang, "score.playedon", t.Format(time.RFC1123))
 			}
 			ret += "\n`" + scoreToString(score.Scores[j]) + timestr + "`"
 		}
@@ -368,7 +973,7 @@ func GetScoreMessage(score ScoreInfo) string {
 }
 
 // ResetScore resets the score for TEAM1:TEAM2 or TEAM1:TEAM2
-func ResetScore(team1 string, team2 string, score1 int, score2 int) error {
+func (db *DB) ResetScore(team1 string, team2 string, score1 int, score2 int) error {
 	var reverse bool
 	team1, team2, reverse = orderTeamNames(team1, team2)
 
@@ -378,6 +983,8 @@ func ResetScore(team1 string, team2 string, score1 int, score2 int) error {
 		score2 = tmp
 	}
 
+	defer db.lockKey(getScoreTag(team1, team2))()
+
 	var scoreInfo ScoreInfo
 	scoreInfo.Team1 = team1
 	scoreInfo.Team2 = team2
@@ -386,18 +993,18 @@ func ResetScore(team1 string, team2 string, score1 int, score2 int) error {
 	js, err := scoreInfoToJSON(scoreInfo)
 
 	if err == nil {
-		err = SetDBValue(getScoreTag(team1, team2), js)
+		err = db.SetDBValue(getScoreTag(team1, team2), js)
 	}
 	return err
 }
 
 // GetScores returns an object with the current scores for TEAM1:TEAM2 or TEAM1:TEAM2
-func GetScores(team1 string, team2 string) (ScoreInfo, error) {
+func (db *DB) GetScores(team1 string, team2 string) (ScoreInfo, error) {
 	var scoreInfo ScoreInfo
 	team1, team2, _ = orderTeamNames(team1, team2)
 
 	tag := getScoreTag(team1, team2)
-	js, err := GetDBValue(tag)
+	js, err := db.GetDBValue(tag)
 
 	if err == nil {
 		scoreInfo, err = jsonToScoreInfo(js)
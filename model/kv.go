@@ -0,0 +1,226 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KVScope selects the namespace of a user key-value pair
+type KVScope struct {
+	Kind string
+	ID   string
+}
+
+// KV scope kinds
+const (
+	ScopeGlobal  = "global"
+	ScopeChannel = "channel"
+	ScopeUser    = "user"
+)
+
+// GlobalScope is shared by the whole workspace
+var GlobalScope = KVScope{Kind: ScopeGlobal}
+
+// ChannelScope returns the namespace of a channel
+func ChannelScope(channel string) KVScope {
+	return KVScope{Kind: ScopeChannel, ID: channel}
+}
+
+// UserScope returns the namespace of a user
+func UserScope(userID string) KVScope {
+	return KVScope{Kind: ScopeUser, ID: userID}
+}
+
+// prefix returns the DB key prefix of the scope. User keys always live under [kv::...] so they can never reach internal records
+func (s KVScope) prefix() string {
+	if s.Kind == ScopeGlobal {
+		return "[kv::global]"
+	}
+	return "[kv::" + s.Kind + "::" + s.ID + "]"
+}
+
+func getKVTag(scope KVScope, key string) string {
+	return scope.prefix() + key
+}
+
+// isInternalKey reports whether a raw DB key belongs to hinko's own records
+func isInternalKey(key string) bool {
+	return strings.HasPrefix(key, "[")
+}
+
+// GetKVValue returns the value of a user key in scope
+func (db *DB) GetKVValue(scope KVScope, key string) (string, error) {
+	tag := getKVTag(scope, key)
+	if db.expired(tag, time.Now()) {
+		return "", errors.New("key has expired")
+	}
+
+	return db.GetDBValue(tag)
+}
+
+// SetKVValue sets the value of a user key in scope, removing any expiry it had. The change is recorded in the key's history
+func (db *DB) SetKVValue(scope KVScope, key string, value string, author string) error {
+	defer db.lockKey(getKVTag(scope, key))()
+	return db.writeKVValue(scope, key, value, author, 0)
+}
+
+// SetKVValueWithTTL sets the value of a user key in scope that is deleted once ttl has passed
+func (db *DB) SetKVValueWithTTL(scope KVScope, key string, value string, author string, ttl time.Duration) error {
+	defer db.lockKey(getKVTag(scope, key))()
+	return db.writeKVValue(scope, key, value, author, ttl)
+}
+
+// writeKVValue writes the value, its history and its expiry (none if ttl is 0) in one batch. The key must be locked
+func (db *DB) writeKVValue(scope KVScope, key string, value string, author string, ttl time.Duration) error {
+	batch := new(Batch)
+
+	err := db.addKVVersion(batch, scope, key, value, author)
+	if err != nil {
+		return err
+	}
+
+	batch.Put(getKVTag(scope, key), value)
+
+	if ttl != 0 {
+		batch.Put(getKVExpiryTag(scope, key), time.Now().Add(ttl).Format(time.RFC3339))
+	} else {
+		batch.Delete(getKVExpiryTag(scope, key))
+	}
+
+	return db.WriteDBBatch(batch)
+}
+
+// DeleteKVValue deletes a user key in scope together with its history
+func (db *DB) DeleteKVValue(scope KVScope, key string) error {
+	defer db.lockKey(getKVTag(scope, key))()
+
+	if !db.KVExists(scope, key) {
+		return errors.New("key not found")
+	}
+
+	batch := new(Batch)
+	batch.Delete(getKVTag(scope, key))
+	batch.Delete(getKVExpiryTag(scope, key))
+	batch.Delete(getKVHistoryTag(scope, key))
+
+	return db.WriteDBBatch(batch)
+}
+
+// KVExists reports whether a user key in scope has a value that hasn't expired
+func (db *DB) KVExists(scope KVScope, key string) bool {
+	_, err := db.GetKVValue(scope, key)
+	return err == nil
+}
+
+// ListKVKeys returns the sorted user keys in scope that start with prefix. Internal records are never listed
+func (db *DB) ListKVKeys(scope KVScope, prefix string) ([]string, error) {
+	now := time.Now()
+	found := make(map[string]bool)
+
+	scopePrefix := scope.prefix()
+	err := db.IterateDBPrefix(scopePrefix+prefix, func(tag string, value string) bool {
+		if !db.expired(tag, now) {
+			found[strings.TrimPrefix(tag, scopePrefix)] = true
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range found {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// DeleteExpiredKVValues removes all user keys whose expiry time has passed, and their history
+func (db *DB) DeleteExpiredKVValues() error {
+	now := time.Now()
+	var expiredTags []string
+
+	err := db.IterateDBPrefix("[kvexpiry::", func(key string, value string) bool {
+		t, err := time.Parse(time.RFC3339, value)
+		if err == nil && now.After(t) {
+			expiredTags = append(expiredTags, strings.TrimSuffix(strings.TrimPrefix(key, "[kvexpiry::"), "]"))
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range expiredTags {
+		unlock := db.lockKey(tag)
+
+		// the key may have been set again since it was found
+		if db.expired(tag, now) {
+			batch := new(Batch)
+			batch.Delete(tag)
+			batch.Delete("[kvexpiry::" + tag + "]")
+			batch.Delete("[kvhistory::" + tag + "]")
+			err = db.WriteDBBatch(batch)
+		}
+
+		unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExpireKVValuesLoop deletes expired user keys every interval. Run it in a goroutine
+func (db *DB) ExpireKVValuesLoop(interval time.Duration) {
+	for {
+		err := db.DeleteExpiredKVValues()
+		if err != nil {
+			fmt.Printf("Deleting expired keys: %s\n", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func getKVExpiryTag(scope KVScope, key string) string {
+	return "[kvexpiry::" + getKVTag(scope, key) + "]"
+}
+
+// expired reports whether the value at a KV tag has passed its expiry time
+func (db *DB) expired(tag string, now time.Time) bool {
+	value, err := db.GetDBValue("[kvexpiry::" + tag + "]")
+	if err != nil {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return err == nil && now.After(t)
+}
@@ -0,0 +1,119 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// KVHistorySize is the number of versions kept for every user key
+var KVHistorySize = 10
+
+// KVVersion is one stored value of a user key
+type KVVersion struct {
+	Version   int
+	Value     string
+	Author    string
+	Timestamp string
+}
+
+func getKVHistoryTag(scope KVScope, key string) string {
+	return "[kvhistory::" + getKVTag(scope, key) + "]"
+}
+
+// GetKVHistory returns the stored versions of a user key, oldest first
+func (db *DB) GetKVHistory(scope KVScope, key string) ([]KVVersion, error) {
+	var history []KVVersion
+
+	js, err := db.GetDBValue(getKVHistoryTag(scope, key))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(js), &history)
+	return history, err
+}
+
+// GetKVVersion returns version n of a user key
+func (db *DB) GetKVVersion(scope KVScope, key string, n int) (KVVersion, error) {
+	history, err := db.GetKVHistory(scope, key)
+	if err != nil {
+		return KVVersion{}, err
+	}
+
+	for _, v := range history {
+		if v.Version == n {
+			return v, nil
+		}
+	}
+
+	return KVVersion{}, errors.New("version not found")
+}
+
+// addKVVersion adds value to the history of a user key in batch, dropping the oldest versions beyond KVHistorySize
+func (db *DB) addKVVersion(batch *Batch, scope KVScope, key string, value string, author string) error {
+	history, err := db.GetKVHistory(scope, key)
+	if err != nil {
+		history = nil
+
+		// values stored before history was kept become version 1, with an unknown author
+		if previous, err := db.GetKVValue(scope, key); err == nil {
+			history = append(history, KVVersion{Version: 1, Value: previous})
+		}
+	}
+
+	version := 1
+	if len(history) > 0 {
+		version = history[len(history)-1].Version + 1
+	}
+
+	history = append(history, KVVersion{Version: version, Value: value, Author: author,
+		Timestamp: time.Now().Format(time.RFC1123)})
+
+	if len(history) > KVHistorySize {
+		history = history[len(history)-KVHistorySize:]
+	}
+
+	js, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	batch.Put(getKVHistoryTag(scope, key), string(js))
+	return nil
+}
+
+// RollbackKVValue makes version n of a user key its current value again, recorded as a new version by author
+func (db *DB) RollbackKVValue(scope KVScope, key string, n int, author string) error {
+	defer db.lockKey(getKVTag(scope, key))()
+
+	v, err := db.GetKVVersion(scope, key, n)
+	if err != nil {
+		return err
+	}
+
+	return db.writeKVValue(scope, key, v.Value, author, 0)
+}
@@ -0,0 +1,87 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"sort"
+	"sync"
+)
+
+// keyLock is a mutex shared by everyone updating the same DB key
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// keyLocks holds the locks of the keys that are being updated
+type keyLocks struct {
+	mutex sync.Mutex
+	locks map[string]*keyLock
+}
+
+// lockKey serializes read-modify-write updates of key and returns the function that releases the lock
+func (db *DB) lockKey(key string) func() {
+	db.locks.mutex.Lock()
+	l := db.locks.locks[key]
+	if l == nil {
+		l = &keyLock{}
+		db.locks.locks[key] = l
+	}
+	l.refs++
+	db.locks.mutex.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+
+		db.locks.mutex.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(db.locks.locks, key)
+		}
+		db.locks.mutex.Unlock()
+	}
+}
+
+// lockKeys locks keys in sorted order, so updates that lock several keys at once can't deadlock,
+// and returns the function that releases them
+func (db *DB) lockKeys(keys ...string) func() {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+
+	var unlocks []func()
+	for i, key := range sorted {
+		if i > 0 && key == sorted[i-1] {
+			continue
+		}
+		unlocks = append(unlocks, db.lockKey(key))
+	}
+
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
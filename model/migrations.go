@@ -0,0 +1,213 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const schemaVersionTag = "[schema::version]"
+
+// Migration upgrades stored data to Version. Run adds its changes to batch and describes them.
+// Migrations must be idempotent, so running one on data that is already converted changes nothing
+type Migration struct {
+	Version     int
+	Description string
+	Run         func(db *DB, batch *Batch) ([]string, error)
+}
+
+// Migrations - all schema migrations, in the order they are applied
+var Migrations = []Migration{
+	{Version: 1, Description: "store groups as JSON records", Run: (*DB).migrateGroupsToRecords},
+	{Version: 2, Description: "move un-namespaced user keys into the global KV scope", Run: (*DB).migrateLegacyKVKeys},
+	{Version: 3, Description: "mark factoid variant lists with a header line", Run: (*DB).migrateVariantLists},
+	{Version: 4, Description: "list recorded draws by time so old ones can be pruned", Run: (*DB).migrateDrawTimes},
+}
+
+// MigrationReport describes what a migration changed, or would change in a dry run
+type MigrationReport struct {
+	Version     int
+	Description string
+	Changes     []string
+}
+
+// GetSchemaVersion returns the schema version of the stored data; databases without one are version 0
+func (db *DB) GetSchemaVersion() int {
+	value, err := db.GetDBValue(schemaVersionTag)
+	if err != nil {
+		return 0
+	}
+	version, _ := strconv.Atoi(value)
+	return version
+}
+
+// LatestSchemaVersion returns the version the last migration upgrades to
+func LatestSchemaVersion() int {
+	return Migrations[len(Migrations)-1].Version
+}
+
+// Migrate runs all migrations newer than the stored schema version, each in its own batch.
+// With dryRun the migrations run against an in-memory copy, so nothing is written and the reports
+// describe what would change, including changes to data that earlier migrations converted
+func (db *DB) Migrate(dryRun bool) ([]MigrationReport, error) {
+	var reports []MigrationReport
+	current := db.GetSchemaVersion()
+
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("database schema version %d is newer than this hinko (%d)", current, LatestSchemaVersion())
+	}
+
+	target := db
+	if dryRun {
+		store := NewMemoryStore()
+		if _, err := CopyStore(db.store, store); err != nil {
+			return nil, err
+		}
+		target = NewDB(store)
+	}
+
+	for _, m := range Migrations {
+		if m.Version <= current {
+			continue
+		}
+
+		batch := new(Batch)
+		changes, err := m.Run(target, batch)
+		if err != nil {
+			return reports, fmt.Errorf("migration %d (%s): %s", m.Version, m.Description, err)
+		}
+
+		reports = append(reports, MigrationReport{Version: m.Version, Description: m.Description, Changes: changes})
+
+		batch.Put(schemaVersionTag, strconv.Itoa(m.Version))
+		if err = target.WriteDBBatch(batch); err != nil {
+			return reports, fmt.Errorf("migration %d (%s): %s", m.Version, m.Description, err)
+		}
+	}
+
+	return reports, nil
+}
+
+func (db *DB) migrateGroupsToRecords(batch *Batch) ([]string, error) {
+	var changes []string
+
+	err := db.IterateDBPrefix("[group::", func(key string, value string) bool {
+		var group GroupInfo
+		if json.Unmarshal([]byte(value), &group) == nil && group.Name != "" {
+			return true
+		}
+
+		group = parseGroupInfo(trimTag(key, "[group::"), value)
+		js, err := json.Marshal(group)
+		if err != nil {
+			return true
+		}
+
+		batch.Put(key, string(js))
+		changes = append(changes, "group "+group.Name+": "+strconv.Itoa(len(group.Members))+" members")
+		return true
+	})
+
+	return changes, err
+}
+
+func (db *DB) migrateLegacyKVKeys(batch *Batch) ([]string, error) {
+	var changes []string
+
+	err := db.IterateDBPrefix("", func(key string, value string) bool {
+		if isInternalKey(key) {
+			return true
+		}
+
+		// a namespaced value written since takes precedence over the legacy one
+		if _, err := db.GetDBValue(getKVTag(GlobalScope, key)); err != nil {
+			batch.Put(getKVTag(GlobalScope, key), value)
+		}
+		batch.Delete(key)
+		changes = append(changes, "key "+key)
+		return true
+	})
+
+	return changes, err
+}
+
+// migrateVariantLists converts variant lists stored as lines starting with + into VariantsHeader lists.
+// Single line values starting with + were stored by plain put and stay as they are
+func (db *DB) migrateVariantLists(batch *Batch) ([]string, error) {
+	var changes []string
+
+	err := db.IterateDBPrefix("[kv::", func(key string, value string) bool {
+		if IsVariantList(value) || !strings.Contains(value, "\n") {
+			return true
+		}
+
+		lines := strings.Split(value, "\n")
+		for i, line := range lines {
+			if !strings.HasPrefix(line, "+") {
+				return true
+			}
+			lines[i] = strings.TrimPrefix(line, "+")
+		}
+
+		batch.Put(key, VariantsHeader+"\n"+strings.Join(lines, "\n"))
+		changes = append(changes, "key "+key+": "+strconv.Itoa(len(lines))+" variants")
+		return true
+	})
+
+	return changes, err
+}
+
+func (db *DB) migrateDrawTimes(batch *Batch) ([]string, error) {
+	var changes []string
+
+	err := db.IterateDBPrefix("[draw::", func(key string, value string) bool {
+		var draw Draw
+		if json.Unmarshal([]byte(value), &draw) != nil || draw.ID == "" {
+			return true
+		}
+
+		tag := getDrawTimeTag(draw.Timestamp, draw.ID)
+		if _, err := db.GetDBValue(tag); err == nil {
+			return true
+		}
+
+		batch.Put(tag, draw.ID)
+		changes = append(changes, "draw "+draw.ID)
+		return true
+	})
+
+	return changes, err
+}
+
+// String formats a report for the console
+func (r MigrationReport) String() string {
+	ret := fmt.Sprintf("%d: %s (%d changes)", r.Version, r.Description, len(r.Changes))
+	if len(r.Changes) > 0 {
+		ret += "\n  " + strings.Join(r.Changes, "\n  ")
+	}
+	return ret
+}
@@ -23,7 +23,15 @@
 package model
 
 import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestGetRandomTeams tests random team generation
@@ -33,10 +41,971 @@ func TestGetRandomTeams(t *testing.T) {
 
 	ret, err := GetRandomTeams(2, members, true, teamNames, false)
 	if err != nil {
-		t.Errorf("Got error calling GetRandomTeams " + string(err.Error()))
+		t.Errorf("Got error calling GetRandomTeams %s", err)
 	}
 
 	if len(ret) < 10 {
 		t.Fail()
 	}
 }
+
+// openTestDatabase returns a DB backed by an empty in-memory store that is closed when the test ends
+func openTestDatabase(t *testing.T) *DB {
+	db := NewDB(NewMemoryStore())
+	t.Cleanup(func() {
+		db.store.Close()
+	})
+	return db
+}
+
+// TestAuditRecords tests storing, filtering and pruning audit records
+func TestAuditRecords(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	var err error
+
+	old := AuditRecord{Timestamp: time.Now().Add(-2 * AuditRetention), UserID: "U1", Command: "put a b", Result: "ok"}
+	if err = db.AddAuditRecord(old); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AddAuditRecord(AuditRecord{UserID: "U1", Command: "score add A:B 1:0", Result: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AddAuditRecord(AuditRecord{UserID: "U2", Command: "put c d", Result: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := db.GetAuditRecords(time.Time{}, nil)
+	if err != nil || len(records) != 2 {
+		t.Errorf("Expected 2 audit records after pruning, got %d (%v)", len(records), err)
+	}
+
+	records, _ = db.GetAuditRecords(time.Time{}, func(r AuditRecord) bool { return r.UserID == "U2" })
+	if len(records) != 1 || records[0].Command != "put c d" {
+		t.Errorf("Expected one record for U2, got %v", records)
+	}
+}
+
+// TestKVScopes tests that user keys are namespaced, listed without internal records and expire
+func TestKVScopes(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	db.SetGroup("devs", []string{"@ana", "@bob"}, "")
+	db.SetKVValue(GlobalScope, "[group::devs]", "garbage", "U1")
+	db.SetKVValue(UserScope("U1"), "lunch", "pizza", "U1")
+	db.SetKVValueWithTTL(GlobalScope, "temp", "x", "U1", -time.Second)
+
+	if members, _ := db.GetGroup("devs"); len(members) != 2 {
+		t.Errorf("Group was overwritten through the KV store: %v", members)
+	}
+
+	if _, err := db.GetKVValue(GlobalScope, "lunch"); err == nil {
+		t.Error("User key leaked into the global scope")
+	}
+
+	if db.KVExists(GlobalScope, "temp") {
+		t.Error("Expired key still exists")
+	}
+
+	keys, _ := db.ListKVKeys(GlobalScope, "")
+	if len(keys) != 1 || keys[0] != "[group::devs]" {
+		t.Errorf("Unexpected global keys %v", keys)
+	}
+
+	if err := db.DeleteExpiredKVValues(); err != nil {
+		t.Error(err)
+	}
+	if _, err := db.GetDBValue(getKVTag(GlobalScope, "temp")); err == nil {
+		t.Error("Expired key wasn't deleted")
+	}
+	if _, err := db.GetKVHistory(GlobalScope, "temp"); err == nil {
+		t.Error("Expired key's history wasn't deleted")
+	}
+}
+
+// TestKVHistory tests bounded version history and rollback
+func TestKVHistory(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	for i := 1; i <= KVHistorySize+2; i++ {
+		db.SetKVValue(GlobalScope, "wifi", "password"+strconv.Itoa(i), "U1")
+	}
+
+	history, err := db.GetKVHistory(GlobalScope, "wifi")
+	if err != nil || len(history) != KVHistorySize || history[0].Version != 3 {
+		t.Fatalf("Unexpected history %v (%v)", history, err)
+	}
+
+	if err = db.RollbackKVValue(GlobalScope, "wifi", 5, "U2"); err != nil {
+		t.Fatal(err)
+	}
+
+	value, _ := db.GetKVValue(GlobalScope, "wifi")
+	latest, _ := db.GetKVVersion(GlobalScope, "wifi", KVHistorySize+3)
+	if value != "password5" || latest.Author != "U2" {
+		t.Errorf("Rollback stored %q by %q", value, latest.Author)
+	}
+
+	if db.RollbackKVValue(GlobalScope, "wifi", 1, "U2") == nil {
+		t.Error("Rolled back to a version that was dropped from the history")
+	}
+
+	// deleting a key drops its history, so a new key with the same name starts at version 1
+	if err = db.DeleteKVValue(GlobalScope, "wifi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.GetKVHistory(GlobalScope, "wifi"); err == nil {
+		t.Error("Deleted key kept its history")
+	}
+	db.SetKVValue(GlobalScope, "wifi", "fresh", "U1")
+	if history, _ := db.GetKVHistory(GlobalScope, "wifi"); len(history) != 1 || history[0].Version != 1 {
+		t.Errorf("Unexpected history after delete %v", history)
+	}
+}
+
+// TestFactoids tests variants and template expansion
+func TestFactoids(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	db.SetGroup("devs", []string{"@ana"}, "")
+	db.AddKVVariant(GlobalScope, "lunch", "Pizza at {random:devs}'s", "U1")
+	db.AddKVVariant(GlobalScope, "lunch", "Pizza at {random:devs}'s", "U1")
+
+	value, _ := db.GetKVValue(GlobalScope, "lunch")
+	if variants := GetVariants(value); len(variants) != 2 {
+		t.Errorf("Expected 2 variants, got %v", variants)
+	}
+
+	db.SetKVValue(GlobalScope, "phone", "+38640123456", "U1")
+	if value, _ := db.GetKVValue(GlobalScope, "phone"); IsVariantList(value) || GetVariants(value)[0] != "+38640123456" {
+		t.Errorf("A plain value starting with + was read as variants: %q", value)
+	}
+
+	ctx := TemplateContext{UserID: "U1", Channel: "C1", Now: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)}
+	if got := db.RenderFactoid(value, ctx); got != "Pizza at @ana's" {
+		t.Errorf("Unexpected factoid %q", got)
+	}
+
+	if got := db.ExpandTemplate("{user} in {channel} on {date} {random:nobody}", ctx); got != "<@U1> in <#C1> on 2026-10-18 {random:nobody}" {
+		t.Errorf("Unexpected template expansion %q", got)
+	}
+}
+
+// TestCounters tests incrementing counters and the karma leaderboard
+func TestCounters(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	db.IncrKVValue(GlobalScope, "coffee", 3, "U1")
+	if n, err := db.IncrKVValue(GlobalScope, "coffee", -1, "U1"); n != 2 || err != nil {
+		t.Errorf("Expected coffee to be 2, got %d (%v)", n, err)
+	}
+
+	db.SetKVValue(GlobalScope, "wifi", "secret", "U1")
+	if _, err := db.IncrKVValue(GlobalScope, "wifi", 1, "U1"); err == nil {
+		t.Error("Incremented a value that isn't a number")
+	}
+
+	db.AddKarma("<@U1>", 1)
+	db.AddKarma("<@U2>", 1)
+	db.AddKarma("<@U2>", 1)
+	db.AddKarma("tests", -1)
+
+	top, _ := db.GetKarmaTop(2)
+	if len(top) != 2 || top[0].Name != "<@U2>" || top[0].Karma != 2 {
+		t.Errorf("Unexpected karma leaderboard %v", top)
+	}
+}
+
+// TestExportImport tests that a dump survives a round trip through a replaced database
+func TestExportImport(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	db.SetGroup("devs", []string{"@ana", "@bob"}, "")
+	db.AddScore("RED", "BLUE", 10, 3)
+	db.SetKVValue(ChannelScope("C1"), "topic", "foosball", "U1")
+	db.AddKarma("<@U1>", 2)
+
+	dump, err := db.ExportDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetGroup("temp", []string{"@x"}, "")
+	if err = db.ImportDatabase(dump, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.GetGroup("temp"); err == nil {
+		t.Error("Replace import kept a group that isn't in the dump")
+	}
+	if members, _ := db.GetGroup("devs"); len(members) != 2 {
+		t.Errorf("Unexpected group after import %v", members)
+	}
+	if scores, _ := db.GetScores("RED", "BLUE"); len(scores.Scores) != 1 {
+		t.Errorf("Unexpected scores after import %v", scores)
+	}
+	if value, _ := db.GetKVValue(ChannelScope("C1"), "topic"); value != "foosball" {
+		t.Errorf("Unexpected KV value after import %q", value)
+	}
+	if db.GetKarma("<@U1>") != 2 {
+		t.Error("Karma wasn't imported")
+	}
+
+	dump.Version = DumpVersion + 1
+	if db.ImportDatabase(dump, false) == nil {
+		t.Error("Imported a dump with an unsupported version")
+	}
+}
+
+// TestMergeImportAudit tests that importing a dump into the database it came from doesn't duplicate audit records
+func TestMergeImportAudit(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	db.AddAuditRecord(AuditRecord{UserID: "U1", Command: "put a b", Result: "ok"})
+	db.AddAuditRecord(AuditRecord{UserID: "U2", Command: "put c d", Result: "ok"})
+
+	dump, err := db.ExportDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.ImportDatabase(dump, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if records, _ := db.GetAuditRecords(time.Time{}, nil); len(records) != 2 {
+		t.Errorf("Merge import duplicated audit records %v", records)
+	}
+}
+
+// TestValidateDump tests that dumps with draws or pair histories that can't be stored are rejected
+func TestValidateDump(t *testing.T) {
+	t.Parallel()
+
+	dump := Dump{Version: DumpVersion, Draws: []Draw{{ID: ""}}}
+	if dump.Validate() == nil {
+		t.Error("Accepted a draw without an ID")
+	}
+
+	dump = Dump{Version: DumpVersion, PairHistories: []PairHistory{{Group: ""}}}
+	if dump.Validate() == nil {
+		t.Error("Accepted a pair history without a group")
+	}
+
+	dump = Dump{Version: DumpVersion, Draws: []Draw{{ID: "abcd"}}, PairHistories: []PairHistory{{Group: "devs"}}}
+	if err := dump.Validate(); err != nil {
+		t.Errorf("Rejected a valid dump: %s", err)
+	}
+}
+
+// TestMigrations tests dry runs and upgrading a database without a schema version
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	db.SetDBValue("[group::devs]", "@ana @bob")
+	db.SetDBValue("wifi", "secret")
+	db.SetDBValue("[kv::global]lunch", "+Pizza\n+Sushi")
+	db.SetDBValue("[kv::global]phone", "+38640123456")
+	db.SetDBValue("menu", "+Pasta\n+Salad")
+
+	reports, err := db.Migrate(true)
+	if err != nil || len(reports) != len(Migrations) || len(reports[0].Changes) != 1 {
+		t.Fatalf("Unexpected dry run %v (%v)", reports, err)
+	}
+	// the variant list migration sees the legacy key that the previous migration moved
+	if len(reports[2].Changes) != 2 {
+		t.Errorf("Dry run didn't see earlier migrations' changes: %v", reports[2].Changes)
+	}
+	if db.GetSchemaVersion() != 0 {
+		t.Error("Dry run changed the schema version")
+	}
+	if value, _ := db.GetDBValue("[group::devs]"); value != "@ana @bob" {
+		t.Error("Dry run changed a group")
+	}
+
+	if _, err = db.Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetSchemaVersion() != LatestSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", LatestSchemaVersion(), db.GetSchemaVersion())
+	}
+
+	group, _ := db.GetGroupInfo("devs")
+	value, _ := db.GetDBValue("[group::devs]")
+	if len(group.Members) != 2 || !strings.HasPrefix(value, "{") {
+		t.Errorf("Group wasn't converted to a record: %q", value)
+	}
+	if value, _ := db.GetKVValue(GlobalScope, "wifi"); value != "secret" {
+		t.Errorf("Legacy key wasn't moved into the global scope: %q", value)
+	}
+	if value, _ := db.GetKVValue(GlobalScope, "lunch"); strings.Join(GetVariants(value), " ") != "Pizza Sushi" {
+		t.Errorf("Legacy variant list wasn't converted: %q", value)
+	}
+	if value, _ := db.GetKVValue(GlobalScope, "phone"); value != "+38640123456" {
+		t.Errorf("Plain value was changed: %q", value)
+	}
+
+	// migrations are idempotent
+	for _, m := range Migrations {
+		changes, _ := m.Run(db, new(Batch))
+		if len(changes) != 0 {
+			t.Errorf("Migration %d changed already migrated data: %v", m.Version, changes)
+		}
+	}
+}
+
+// TestConcurrentScores hammers AddScore and AddToGroup from many goroutines and checks that no update is lost
+func TestConcurrentScores(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	const workers = 20
+	const matches = 25
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < matches; i++ {
+				// alternate the team order, both map to the same record
+				if i%2 == 0 {
+					db.AddScore("RED", "BLUE", 10, i%10)
+				} else {
+					db.AddScore("BLUE", "RED", i%10, 10)
+				}
+			}
+			db.AddToGroup("players", []string{"@player" + strconv.Itoa(w)}, "")
+		}(w)
+	}
+	wg.Wait()
+
+	scores, err := db.GetScores("RED", "BLUE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores.Scores) != workers*matches {
+		t.Errorf("Expected %d matches, got %d", workers*matches, len(scores.Scores))
+	}
+	if scores.Points.Team2 != workers*matches {
+		t.Errorf("Expected RED to have %d points, got %d", workers*matches, scores.Points.Team2)
+	}
+
+	if members, _ := db.GetGroup("players"); len(members) != workers {
+		t.Errorf("Expected %d group members, got %d", workers, len(members))
+	}
+}
+
+// TestSnapshotRetention tests which snapshots are kept for hourly and daily retention
+func TestSnapshotRetention(t *testing.T) {
+	var snapshots []Snapshot
+	start := time.Date(2026, 10, 18, 23, 50, 0, 0, time.UTC)
+
+	// two snapshots per hour for three days, newest first
+	for i := 0; i < 144; i++ {
+		s := start.Add(-time.Duration(i) * 30 * time.Minute)
+		snapshots = append(snapshots, Snapshot{Name: s.Format(legacySnapshotTimeFormat), Time: s})
+	}
+
+	keep := snapshotsToKeep(snapshots, 3, 2)
+
+	// the newest three hours, plus the newest snapshot of the previous day
+	expected := []string{"20261018T235000", "20261018T225000", "20261018T215000", "20261017T235000"}
+	if len(keep) != len(expected) {
+		t.Errorf("Expected to keep %v, kept %v", expected, keep)
+	}
+	for _, name := range expected {
+		if !keep[name] {
+			t.Errorf("Snapshot %s wasn't kept", name)
+		}
+	}
+}
+
+// TestCreateSnapshot tests that snapshots written in the same second don't overwrite each other
+// and that snapshots named with second resolution are still listed
+func TestCreateSnapshot(t *testing.T) {
+	dir := BackupDir
+	BackupDir = t.TempDir()
+	t.Cleanup(func() { BackupDir = dir })
+
+	db := openTestDatabase(t)
+	db.SetGroup("devs", []string{"@ana"}, "")
+
+	for i := 0; i < 3; i++ {
+		if _, err := db.CreateSnapshot(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(BackupDir, snapshotPrefix+"20190101T120000"+snapshotSuffix), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := ListSnapshots()
+	if err != nil || len(snapshots) != 4 {
+		t.Fatalf("Expected 4 snapshots, got %v (%v)", snapshots, err)
+	}
+	if snapshots[3].Time.Year() != 2019 {
+		t.Errorf("Expected the second resolution snapshot to be the oldest, got %v", snapshots)
+	}
+
+	files, _ := ioutil.ReadDir(BackupDir)
+	if len(files) != 4 {
+		t.Errorf("Expected no temporary files to be left, got %d files", len(files))
+	}
+}
+
+// TestNestedGroups tests group references, cycle detection and group expressions
+func TestNestedGroups(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	_ = db.SetGroup("frontend", []string{"ana", "bob"}, "")
+	_ = db.SetGroup("backend", []string{"bob", "cene"}, "")
+	_ = db.SetGroup("interns", []string{"cene"}, "")
+
+	if err := db.SetGroup("engineering", []string{"%frontend", "%backend", "dora"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := db.ResolveGroup("engineering")
+	if err != nil || strings.Join(members, " ") != "ana bob cene dora" {
+		t.Errorf("Expected ana bob cene dora, got %v (%v)", members, err)
+	}
+
+	if err := db.AddToGroup("frontend", []string{"%engineering"}, ""); err != ErrGroupCycle {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+	if err := db.AddToGroup("frontend", []string{"%frontend"}, ""); err != ErrGroupCycle {
+		t.Errorf("Expected a cycle error for a self reference, got %v", err)
+	}
+	if err := db.AddToGroup("frontend", []string{"%missing"}, ""); err == nil {
+		t.Error("Expected an error for a missing group")
+	}
+
+	members, err = db.ResolveGroupExpression("frontend+backend-interns")
+	if err != nil || strings.Join(members, " ") != "ana bob" {
+		t.Errorf("Expected ana bob, got %v (%v)", members, err)
+	}
+
+	_ = db.SetGroup("engineering-interns", []string{"eva"}, "")
+	members, _ = db.ResolveGroupExpression("engineering-interns")
+	if strings.Join(members, " ") != "eva" {
+		t.Errorf("Expected an existing group name to take precedence, got %v", members)
+	}
+
+	if _, err = db.ResolveGroupExpression("frontend+nobody"); err == nil {
+		t.Error("Expected an error for a missing group in an expression")
+	}
+}
+
+// TestConcurrentGroupRefs tests that two groups including each other at the same time can't create a cycle
+func TestConcurrentGroupRefs(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	for i := 0; i < 200; i++ {
+		a, b := "a"+strconv.Itoa(i), "b"+strconv.Itoa(i)
+		_ = db.SetGroup(a, []string{"ana"}, "")
+		_ = db.SetGroup(b, []string{"bob"}, "")
+
+		var wg sync.WaitGroup
+		start := make(chan bool)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			_ = db.AddToGroup(a, []string{GroupRefPrefix + b}, "")
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			_ = db.AddToGroup(b, []string{GroupRefPrefix + a}, "")
+		}()
+		close(start)
+		wg.Wait()
+
+		if _, err := db.ResolveGroup(a); err == ErrGroupCycle {
+			t.Fatalf("Concurrent adds created a cycle between %s and %s", a, b)
+		}
+	}
+}
+
+// TestGroupLifecycle tests group metadata, listing, renaming, copying and deleting
+func TestGroupLifecycle(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	_ = db.SetGroup("frontend", []string{"ana", "bob"}, "U1")
+	_ = db.AddToGroup("engineering", []string{"%frontend"}, "U2")
+	_ = db.DescribeGroup("frontend", "web people")
+
+	_ = db.SetGroup("interns", []string{"eva", "", "eva", "fran"}, "U1")
+	if members, _ := db.GetGroup("interns"); strings.Join(members, " ") != "eva fran" {
+		t.Errorf("Expected empty and repeated members to be dropped, got %v", members)
+	}
+	_ = db.SetGroupAllowNonUsers("interns", true)
+	if group, _ := db.GetGroupInfo("interns"); !group.AllowNonUsers {
+		t.Error("Expected the group to allow non-user members")
+	}
+	_ = db.DeleteGroup("interns")
+
+	group, err := db.GetGroupInfo("frontend")
+	if err != nil || group.Owner != "U1" || group.Description != "web people" || group.Created == "" || group.Updated == "" {
+		t.Errorf("Unexpected group record %+v (%v)", group, err)
+	}
+
+	_ = db.SetChannelSetting("C1", SettingGroup, "frontend")
+	_ = db.SetChannelSetting("C2", SettingGroup, "frontend")
+	_ = db.SetChannelSetting("C2", SettingTeamSize, "2")
+	_ = db.AddPairRound("frontend", "d1", [][]string{{"ana", "bob"}}, false)
+
+	if err = db.RenameGroup("frontend", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetChannelSettings("C1").DefaultGroup != "web" {
+		t.Error("Channel default group wasn't renamed")
+	}
+	if history, _ := db.GetPairHistory("web"); len(history.Rounds) != 1 {
+		t.Errorf("Pair history wasn't renamed, got %+v", history)
+	}
+	if _, err = db.GetGroupInfo("frontend"); err == nil {
+		t.Error("Old group name still exists after rename")
+	}
+	members, _ := db.ResolveGroup("engineering")
+	if strings.Join(members, " ") != "ana bob" {
+		t.Errorf("Reference wasn't renamed, engineering resolves to %v", members)
+	}
+
+	if err = db.CopyGroup("web", "engineering", "U3"); err == nil {
+		t.Error("Expected copy onto an existing group to fail")
+	}
+	if err = db.CopyGroup("web", "web2", "U3"); err != nil {
+		t.Fatal(err)
+	}
+	group, _ = db.GetGroupInfo("web2")
+	if group.Owner != "U3" || group.Description != "web people" || len(group.Members) != 2 {
+		t.Errorf("Unexpected copy %+v", group)
+	}
+
+	_ = db.SetGroup("colors", []string{"red", "blue"}, "U1")
+	_ = db.SetGroupAllowNonUsers("colors", true)
+	_ = db.PauseMembers("colors", []string{"red"}, "")
+	if err = db.CopyGroup("colors", "colors2", "U3"); err != nil {
+		t.Fatal(err)
+	}
+	group, _ = db.GetGroupInfo("colors2")
+	if !group.AllowNonUsers || group.Owner != "U3" || len(group.PausedMembers()) != 1 {
+		t.Errorf("Expected the copy to keep the group's settings and pauses, got %+v", group)
+	}
+	_ = db.DeleteGroup("colors")
+	_ = db.DeleteGroup("colors2")
+
+	if err = db.DeleteGroup("web"); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.DeleteGroup("web"); err == nil {
+		t.Error("Expected deleting a missing group to fail")
+	}
+	if group, _ = db.GetGroupInfo("engineering"); len(group.Members) != 0 {
+		t.Errorf("Expected the reference to the deleted group to be removed, got %v", group.Members)
+	}
+	if db.GetChannelSettings("C1") != (ChannelSettings{}) || db.GetChannelSettings("C2") != (ChannelSettings{TeamSize: 2}) {
+		t.Errorf("Expected the deleted group to be cleared from channel settings, got %+v %+v",
+			db.GetChannelSettings("C1"), db.GetChannelSettings("C2"))
+	}
+	if history, _ := db.GetPairHistory("web"); len(history.Rounds) != 0 {
+		t.Errorf("Expected the pair history to be deleted, got %+v", history)
+	}
+
+	groups, _ := db.ListGroups()
+	var names []string
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	if strings.Join(names, " ") != "engineering web2" {
+		t.Errorf("Expected engineering web2, got %v", names)
+	}
+
+	dump, _ := db.ExportDatabase()
+	_ = db.ImportDatabase(dump, true)
+	group, _ = db.GetGroupInfo("web2")
+	if group.Owner != "U3" || group.Description != "web people" {
+		t.Errorf("Group metadata lost in export and import: %+v", group)
+	}
+}
+
+// TestPausedMembers tests that paused members are skipped in draws until their pause ends
+func TestPausedMembers(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	_ = db.SetGroup("devs", []string{"ana", "bob", "cene"}, "")
+	_ = db.SetGroup("all", []string{"%devs", "dora"}, "")
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(PauseDateFormat)
+	if err := db.PauseMembers("devs", []string{"ana"}, tomorrow); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PauseMembers("devs", []string{"bob"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PauseMembers("devs", []string{"nobody"}, ""); err == nil {
+		t.Error("Expected pausing a non-member to fail")
+	}
+	if err := db.PauseMembers("devs", []string{"cene"}, "someday"); err == nil {
+		t.Error("Expected an invalid date to fail")
+	}
+	if err := db.PauseMembers("devs", []string{"cene"}, time.Now().AddDate(0, 0, -1).Format(PauseDateFormat)); err == nil {
+		t.Error("Expected a date in the past to fail")
+	}
+
+	members, _ := db.ResolveGroup("all")
+	if strings.Join(members, " ") != "cene dora" {
+		t.Errorf("Expected cene dora, got %v", members)
+	}
+
+	group, _ := db.GetGroupInfo("devs")
+	if len(group.Members) != 3 || group.PausedMembers()["ana"] != tomorrow {
+		t.Errorf("Unexpected group record %+v", group)
+	}
+
+	// a pause that ended yesterday no longer applies
+	group.Paused["ana"] = time.Now().AddDate(0, 0, -1).Format(PauseDateFormat)
+	_ = db.setGroupInfo(group)
+	_ = db.ResumeMembers("devs", []string{"bob"})
+
+	members, _ = db.ResolveGroup("devs")
+	if strings.Join(members, " ") != "ana bob cene" {
+		t.Errorf("Expected ana bob cene, got %v", members)
+	}
+	group, _ = db.GetGroupInfo("devs")
+	if group.Paused != nil {
+		t.Errorf("Expected ended pauses to be dropped, got %v", group.Paused)
+	}
+
+	// removing a member ends their pause, so adding them back makes them active
+	_ = db.PauseMembers("devs", []string{"cene"}, "")
+	_ = db.RemoveFromGroup("devs", []string{"cene"})
+	_ = db.AddToGroup("devs", []string{"cene"}, "")
+	if members, _ = db.ResolveGroup("devs"); strings.Join(members, " ") != "ana bob cene" {
+		t.Errorf("Expected the removed member's pause to be dropped, got %v", members)
+	}
+}
+
+// TestGroupLinks tests syncing groups with Slack user groups in both directions
+func TestGroupLinks(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	_ = db.SetGroup("interns", []string{"<@U9>"}, "")
+	_ = db.SetGroup("backend", []string{"<@U1>", "<@U2>", "%interns"}, "")
+
+	// one-way: Slack wins, non-user members stay
+	_ = db.LinkGroup("backend", "S1", false)
+	if err := db.SyncGroup("backend", []string{"U2", "U3"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	members, _ := db.GetGroup("backend")
+	if strings.Join(members, " ") != "%interns <@U2> <@U3>" {
+		t.Errorf("Expected %%interns <@U2> <@U3>, got %v", members)
+	}
+
+	_ = db.AddToGroup("backend", []string{"<@U4>"}, "")
+	group, _ := db.GetGroupInfo("backend")
+	onlyLocal, onlyRemote := group.LinkConflicts()
+	if strings.Join(onlyLocal, " ") != "U4" || len(onlyRemote) != 0 {
+		t.Errorf("Expected U4 to be a conflict, got %v %v", onlyLocal, onlyRemote)
+	}
+
+	// two-way: the first sync pushes the union, then hinko adds U6 while Slack removes U2 and adds U5
+	var pushed []string
+	push := func(members []string) error {
+		pushed = members
+		return nil
+	}
+
+	_ = db.LinkGroup("backend", "S1", true)
+	_ = db.SyncGroup("backend", []string{"U2", "U3"}, push)
+	if strings.Join(pushed, " ") != "U2 U3 U4" {
+		t.Errorf("Expected U2 U3 U4 to be pushed, got %v", pushed)
+	}
+
+	_ = db.AddToGroup("backend", []string{"<@U6>"}, "")
+	err := db.SyncGroup("backend", []string{"U3", "U4", "U5"}, push)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(pushed, " ") != "U3 U4 U6 U5" {
+		t.Errorf("Expected U3 U4 U6 U5 to be pushed, got %v", pushed)
+	}
+	group, _ = db.GetGroupInfo("backend")
+	if onlyLocal, onlyRemote = group.LinkConflicts(); len(onlyLocal)+len(onlyRemote) != 0 {
+		t.Errorf("Expected no conflicts after a push, got %v %v", onlyLocal, onlyRemote)
+	}
+
+	// push runs outside the group's lock, so the group can change meanwhile, and a failed push is kept for the next sync
+	_ = db.RemoveFromGroup("backend", []string{"<@U6>"})
+	err = db.SyncGroup("backend", []string{"U3", "U4", "U5", "U6"}, func(members []string) error {
+		return db.AddToGroup("backend", []string{"<@U7>"}, "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.SyncGroup("backend", []string{"U3", "U4", "U5"}, func(members []string) error {
+		return ErrNotFound
+	})
+	group, _ = db.GetGroupInfo("backend")
+	if err != ErrNotFound || group.Link.Error != ErrNotFound.Error() || strings.Join(group.Link.Members, " ") != "U3 U4 U5" {
+		t.Errorf("Expected the failed push to be recorded, got %v %+v", err, group.Link)
+	}
+
+	linked, _ := db.GetLinkedGroups("S1")
+	if len(linked) != 1 || linked[0].Name != "backend" {
+		t.Errorf("Expected backend to be linked to S1, got %v", linked)
+	}
+
+	_ = db.UnlinkGroup("backend")
+	if linked, _ = db.GetLinkedGroups(""); len(linked) != 0 {
+		t.Errorf("Expected no linked groups, got %v", linked)
+	}
+}
+
+// TestChannelSettings tests storing, validating and clearing channel settings
+func TestChannelSettings(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	_ = db.SetGroup("foosball", []string{"ana", "bob", "cene", "dora"}, "")
+
+	if err := db.SetChannelSetting("C1", SettingGroup, "missing"); err == nil {
+		t.Error("Expected a missing default group to be rejected")
+	}
+	if err := db.SetChannelSetting("C1", SettingTeamSize, "0"); err == nil {
+		t.Error("Expected team size 0 to be rejected")
+	}
+	if err := db.SetChannelSetting("C1", SettingMatchup, "red"); err == nil {
+		t.Error("Expected an invalid matchup to be rejected")
+	}
+	if err := db.SetChannelSetting("C1", "colour", "red"); err == nil {
+		t.Error("Expected an unknown setting to be rejected")
+	}
+
+	_ = db.SetChannelSetting("C1", SettingGroup, "foosball")
+	_ = db.SetChannelSetting("C1", SettingTeamSize, "2")
+	_ = db.SetChannelSetting("C1", SettingMatchup, "red:blue")
+	_ = db.SetChannelSetting("C1", SettingAnimations, "off")
+
+	settings := db.GetChannelSettings("C1")
+	expected := ChannelSettings{DefaultGroup: "foosball", TeamSize: 2, ScoreMatchup: "RED:BLUE", AnimationsDisabled: true}
+	if settings != expected {
+		t.Errorf("Expected %+v, got %+v", expected, settings)
+	}
+	if db.GetChannelSettings("C2") != (ChannelSettings{}) {
+		t.Error("Expected settings of another channel to be unset")
+	}
+
+	for _, name := range SettingNames {
+		_ = db.SetChannelSetting("C1", name, "")
+	}
+	if _, err := db.GetDBValue(getChannelSettingsTag("C1")); err == nil {
+		t.Error("Expected the settings record to be deleted once nothing is set")
+	}
+}
+
+// TestDraws tests that draws are reproducible from their seed and can be verified after being recorded
+func TestDraws(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	members := []string{"ana", "bob", "cene", "dora", "eva", "fran", "gal"}
+	seed := NewSeed()
+
+	teams1, err := DrawTeamList(2, members, true, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams2, _ := DrawTeamList(2, members, true, seed)
+	if fmt.Sprint(teams1) != fmt.Sprint(teams2) {
+		t.Errorf("Same seed gave different teams: %v and %v", teams1, teams2)
+	}
+	if strings.Join(members, " ") != "ana bob cene dora eva fran gal" {
+		t.Errorf("Drawing changed the members: %v", members)
+	}
+
+	if _, err = DrawTeamList(2, members, true, "not hex"); err == nil {
+		t.Error("Expected an invalid seed to fail")
+	}
+
+	names := []string{"red", "blue", "green", "yellow"}
+	shuffled := Draw{Seed: seed}.ShuffleTeamNames(names)
+	if fmt.Sprint(shuffled) != fmt.Sprint(Draw{Seed: seed}.ShuffleTeamNames(names)) {
+		t.Error("Same seed gave different team names")
+	}
+	if strings.Join(names, " ") != "red blue green yellow" {
+		t.Errorf("Shuffling changed the team names: %v", names)
+	}
+
+	draw, err := db.RecordDraw(Draw{Kind: DrawPairs, Seed: seed, TeamSize: 2, Repeat: true, Members: members, Teams: teams1})
+	if err != nil || draw.ID == "" {
+		t.Fatalf("Recording failed: %v", err)
+	}
+
+	draw, err = db.GetDraw(draw.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := draw.Verify(); !ok || err != nil {
+		t.Errorf("Expected the recorded draw to verify (%v)", err)
+	}
+
+	draw.Teams[0][0], draw.Teams[1][0] = draw.Teams[1][0], draw.Teams[0][0]
+	if ok, _ := draw.Verify(); ok {
+		t.Error("Expected tampered teams not to verify")
+	}
+
+	old, _ := db.RecordDraw(Draw{Kind: DrawPairs, Seed: seed, Members: members, Timestamp: time.Now().Add(-2 * DrawRetention)})
+	_, _ = db.RecordDraw(Draw{Kind: DrawPairs, Seed: seed, Members: members})
+	if _, err = db.GetDraw(old.ID); err == nil {
+		t.Error("Expected a draw older than DrawRetention to be pruned")
+	}
+	if _, err = db.GetDraw(draw.ID); err != nil {
+		t.Errorf("Expected a recent draw to be kept (%v)", err)
+	}
+}
+
+// TestPairings tests pairing history, fresh draws that avoid recent pairs and round-robin rotations
+func TestPairings(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	members := []string{"ana", "bob", "cene", "dora", "eva", "fran"}
+
+	// a full rotation pairs everyone with everyone else exactly once
+	for round := 0; round < len(members)-1; round++ {
+		pairs, err := RotatePairs(members, round)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = db.AddPairRound("devs", "", pairs, true)
+	}
+
+	history, _ := db.GetPairHistory("devs")
+	if history.Rotation != len(members)-1 {
+		t.Errorf("Expected rotation %d, got %d", len(members)-1, history.Rotation)
+	}
+	counts := history.PairCounts(0)
+	for i, a := range members {
+		for _, b := range members[i+1:] {
+			if PairCount(counts, a, b) != 1 {
+				t.Errorf("%s and %s were paired %d times", a, b, PairCount(counts, a, b))
+			}
+		}
+	}
+
+	pairs, _ := RotatePairs(members[:5], 0)
+	if len(pairs) != 2 || len(pairs[1]) != 3 {
+		t.Errorf("Expected the leftover member to join the last pair, got %v", pairs)
+	}
+
+	// after pairing ana with bob, cene with dora and eva with fran, a fresh draw avoids all of those pairs
+	avoid := map[string]int{pairKey("ana", "bob"): 1, pairKey("cene", "dora"): 1, pairKey("eva", "fran"): 1}
+	seed := NewSeed()
+	fresh, err := DrawFreshPairs(members, seed, avoid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range fresh {
+		if avoid[pairKey(pair[0], pair[1])] > 0 {
+			t.Errorf("Fresh draw repeated %v", pair)
+		}
+	}
+
+	draw := Draw{Kind: DrawPairs, Mode: DrawModeFresh, Seed: seed, Members: members, Avoid: avoid, Teams: fresh}
+	if ok, _ := draw.Verify(); !ok {
+		t.Error("Expected the fresh draw to verify")
+	}
+
+	if _, err = DrawFreshPairs(members[:3], seed, nil); err == nil || !strings.Contains(err.Error(), "four") {
+		t.Errorf("Unexpected error for a fresh draw from three members: %v", err)
+	}
+
+	// concurrent rotations from the same group each get their own round
+	var wg sync.WaitGroup
+	rounds := make(chan int, 10)
+	for i := 0; i < cap(rounds); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = db.DrawPairRound("web", func(history PairHistory) (Draw, error) {
+				rounds <- history.Rotation
+				return Draw{Mode: DrawModeRotate}, nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(rounds)
+
+	seen := make(map[int]bool)
+	for round := range rounds {
+		if seen[round] {
+			t.Errorf("Rotation round %d was drawn twice", round)
+		}
+		seen[round] = true
+	}
+}
+
+// TestBalancedTeams tests ratings from recorded matches and manual ratings, and that balanced draws are even and verifiable
+func TestBalancedTeams(t *testing.T) {
+	t.Parallel()
+	db := openTestDatabase(t)
+
+	for i := 0; i < 5; i++ {
+		_ = db.AddScore("<@A>+<@B>", "<@C>+<@D>", 10, 2)
+	}
+	_ = db.SetRating("<@E>", 1200)
+
+	members := []string{"<@A>", "<@B>", "<@C>", "<@D>", "<@E>", "<@F>"}
+	ratings, err := db.GetRatings(members)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(ratings["<@A>"] > DefaultRating && ratings["<@C>"] < DefaultRating) {
+		t.Errorf("Expected winners to gain and losers to lose rating, got %v", ratings)
+	}
+	if ratings["<@E>"] != 1200 || ratings["<@F>"] != DefaultRating {
+		t.Errorf("Expected manual and default ratings, got %v", ratings)
+	}
+
+	seed := NewSeed()
+	teams, err := DrawBalancedTeams(3, members, ratings, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the best split puts the two strongest players in different teams
+	best := math.Inf(1)
+	for _, team := range [][]string{{"<@A>", "<@C>", "<@E>"}, {"<@A>", "<@D>", "<@E>"}, {"<@A>", "<@C>", "<@F>"}, {"<@A>", "<@D>", "<@F>"}} {
+		var other []string
+		for _, m := range members {
+			if !Contains(team, m) {
+				other = append(other, m)
+			}
+		}
+		best = math.Min(best, strengthSpread([][]string{team, other}, ratings))
+	}
+	if spread := strengthSpread(teams, ratings); spread > best+BalanceTolerance {
+		t.Errorf("Teams %v have spread %f, best is %f", teams, spread, best)
+	}
+
+	draw := Draw{Kind: DrawTeams, Mode: DrawModeBalanced, Seed: seed, TeamSize: 3, Members: members, Ratings: ratings, Teams: teams}
+	if ok, _ := draw.Verify(); !ok {
+		t.Error("Expected the balanced draw to verify")
+	}
+}
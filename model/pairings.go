@@ -0,0 +1,256 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// PairHistorySize is the number of rounds kept in the pairing history of a group
+var PairHistorySize = 50
+
+// FreshPairRounds is the number of recent rounds whose pairings fresh draws avoid
+var FreshPairRounds = 10
+
+// freshPairCandidates is the number of random pairings a fresh draw picks the best one from
+const freshPairCandidates = 500
+
+// Draw modes of randompairs
+const (
+	DrawModeFresh  = "fresh"
+	DrawModeRotate = "rotate"
+)
+
+// PairRound is one recorded randompairs draw of a group
+type PairRound struct {
+	DrawID    string `json:",omitempty"`
+	Timestamp time.Time
+	Pairs     [][]string
+}
+
+// PairHistory is the pairing history of a group, newest round last
+type PairHistory struct {
+	Group    string
+	Rounds   []PairRound
+	Rotation int `json:",omitempty"`
+}
+
+func getPairHistoryTag(group string) string {
+	return "[pairs::" + group + "]"
+}
+
+// GetPairHistory returns the pairing history of group, which is empty if nothing was recorded
+func (db *DB) GetPairHistory(group string) (PairHistory, error) {
+	history := PairHistory{Group: group}
+
+	value, err := db.GetDBValue(getPairHistoryTag(group))
+	if err == ErrNotFound {
+		return history, nil
+	}
+	if err != nil {
+		return history, err
+	}
+
+	err = json.Unmarshal([]byte(value), &history)
+	return history, err
+}
+
+// AddPairRound appends pairs drawn for group to its history, keeping the last PairHistorySize rounds.
+// Rounds drawn with DrawModeRotate advance the rotation
+func (db *DB) AddPairRound(group string, drawID string, pairs [][]string, rotated bool) error {
+	defer db.lockKey(getPairHistoryTag(group))()
+
+	history, err := db.GetPairHistory(group)
+	if err != nil {
+		return err
+	}
+
+	return db.addPairRound(history, drawID, pairs, rotated)
+}
+
+// DrawPairRound calls draw with the pairing history of group and appends the pairs it draws to the history.
+// The history stays locked in between, so two draws from the same group can't both use the same rotation round
+func (db *DB) DrawPairRound(group string, draw func(history PairHistory) (Draw, error)) (Draw, error) {
+	defer db.lockKey(getPairHistoryTag(group))()
+
+	history, err := db.GetPairHistory(group)
+	if err != nil {
+		return Draw{}, err
+	}
+
+	d, err := draw(history)
+	if err != nil {
+		return d, err
+	}
+
+	return d, db.addPairRound(history, d.ID, d.Teams, d.Mode == DrawModeRotate)
+}
+
+func (db *DB) addPairRound(history PairHistory, drawID string, pairs [][]string, rotated bool) error {
+	history.Rounds = append(history.Rounds, PairRound{DrawID: drawID, Timestamp: time.Now(), Pairs: pairs})
+	if len(history.Rounds) > PairHistorySize {
+		history.Rounds = history.Rounds[len(history.Rounds)-PairHistorySize:]
+	}
+	if rotated {
+		history.Rotation++
+	}
+
+	js, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return db.SetDBValue(getPairHistoryTag(history.Group), string(js))
+}
+
+func pairKey(a string, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// PairCounts returns how many times each two members were in the same pair in the last rounds rounds (all if rounds is 0)
+func (history PairHistory) PairCounts(rounds int) map[string]int {
+	counts := make(map[string]int)
+
+	start := 0
+	if rounds > 0 && len(history.Rounds) > rounds {
+		start = len(history.Rounds) - rounds
+	}
+
+	for _, round := range history.Rounds[start:] {
+		for _, pair := range round.Pairs {
+			for i := 0; i < len(pair); i++ {
+				for j := i + 1; j < len(pair); j++ {
+					if pair[i] != pair[j] {
+						counts[pairKey(pair[i], pair[j])]++
+					}
+				}
+			}
+		}
+	}
+
+	return counts
+}
+
+// PairCount returns how many times a and b were paired according to counts from PairCounts
+func PairCount(counts map[string]int, a string, b string) int {
+	return counts[pairKey(a, b)]
+}
+
+// Members returns everyone who appears in the history, sorted
+func (history PairHistory) Members() []string {
+	var members []string
+	for _, round := range history.Rounds {
+		for _, pair := range round.Pairs {
+			for _, m := range pair {
+				if !Contains(members, m) {
+					members = append(members, m)
+				}
+			}
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// DrawFreshPairs draws pairs of members with seed, picking the pairing with the fewest repeats of the pairs counted in avoid
+func DrawFreshPairs(members []string, seed string, avoid map[string]int) ([][]string, error) {
+	if len(members) < 4 {
+		return nil, errors.New("Fresh pairs need at least four members")
+	}
+
+	rng, err := newSeededRand(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	var best [][]string
+	bestCost := -1
+
+	for i := 0; i < freshPairCandidates && bestCost != 0; i++ {
+		pairs := drawTeamList(2, members, true, rng)
+
+		cost := 0
+		for _, pair := range pairs {
+			for a := 0; a < len(pair); a++ {
+				for b := a + 1; b < len(pair); b++ {
+					cost += avoid[pairKey(pair[a], pair[b])]
+				}
+			}
+		}
+
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = pairs, cost
+		}
+	}
+
+	return best, nil
+}
+
+// RotatePairs returns round of a round-robin rotation of members (circle method): within len(members)-1 consecutive rounds
+// everyone is paired with everyone else once. With an odd number of members, the one left over joins the last pair
+func RotatePairs(members []string, round int) ([][]string, error) {
+	if len(members) < 2 {
+		return nil, errors.New("Rotation needs at least two members")
+	}
+
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+	if len(sorted)%2 != 0 {
+		sorted = append(sorted, "")
+	}
+
+	n := len(sorted)
+	circle := []string{sorted[0]}
+	for i := 0; i < n-1; i++ {
+		circle = append(circle, sorted[1+(i+round)%(n-1)])
+	}
+
+	var pairs [][]string
+	leftover := ""
+	for i := 0; i < n/2; i++ {
+		a, b := circle[i], circle[n-1-i]
+		switch {
+		case a == "":
+			leftover = b
+		case b == "":
+			leftover = a
+		default:
+			pairs = append(pairs, []string{a, b})
+		}
+	}
+
+	if leftover != "" {
+		if len(pairs) == 0 {
+			return nil, errors.New("Rotation needs at least two members")
+		}
+		pairs[len(pairs)-1] = append(pairs[len(pairs)-1], leftover)
+	}
+
+	return pairs, nil
+}
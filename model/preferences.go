@@ -0,0 +1,69 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"github.com/tadej/hinko/i18n"
+)
+
+func getUserLanguageTag(userID string) string {
+	return "[language::user::" + userID + "]"
+}
+
+func getChannelLanguageTag(channel string) string {
+	return "[language::channel::" + channel + "]"
+}
+
+// GetUserLanguage returns the language preference of a user, or "" if none is set
+func (db *DB) GetUserLanguage(userID string) string {
+	lang, _ := db.GetDBValue(getUserLanguageTag(userID))
+	return lang
+}
+
+// GetChannelLanguage returns the language preference of a channel, or "" if none is set
+func (db *DB) GetChannelLanguage(channel string) string {
+	lang, _ := db.GetDBValue(getChannelLanguageTag(channel))
+	return lang
+}
+
+// SetUserLanguage stores the language preference of a user
+func (db *DB) SetUserLanguage(userID string, lang string) error {
+	return db.SetDBValue(getUserLanguageTag(userID), lang)
+}
+
+// SetChannelLanguage stores the language preference of a channel
+func (db *DB) SetChannelLanguage(channel string, lang string) error {
+	return db.SetDBValue(getChannelLanguageTag(channel), lang)
+}
+
+// GetLanguage returns the language to respond in: the user's preference, then the channel's, then the default
+func (db *DB) GetLanguage(userID string, channel string) string {
+	if lang := db.GetUserLanguage(userID); i18n.Supported(lang) {
+		return lang
+	}
+	if lang := db.GetChannelLanguage(channel); i18n.Supported(lang) {
+		return lang
+	}
+	return i18n.DefaultLanguage
+}
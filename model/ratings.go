@@ -0,0 +1,268 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRating is the rating of players without recorded matches or a manual rating
+var DefaultRating = 1500.0
+
+// EloK is how much a single match changes the ratings of its players
+var EloK = 32.0
+
+// BalanceTolerance is how much weaker than the best split a balanced draw may be, in rating points
+var BalanceTolerance = 20.0
+
+// balancedCandidates is the number of random starting splits a balanced draw improves and picks from
+const balancedCandidates = 200
+
+// DrawModeBalanced is the draw mode of randomteams --balanced
+const DrawModeBalanced = "balanced"
+
+func getRatingTag(player string) string {
+	return "[rating::" + strings.ToUpper(player) + "]"
+}
+
+// SetRating sets the manual rating of player, which takes precedence over the rating from recorded matches
+func (db *DB) SetRating(player string, rating int) error {
+	return db.SetDBValue(getRatingTag(player), strconv.Itoa(rating))
+}
+
+// DeleteRating removes the manual rating of player
+func (db *DB) DeleteRating(player string) error {
+	return db.DeleteDBValue(getRatingTag(player))
+}
+
+// GetManualRating returns the manual rating of player, if one is set
+func (db *DB) GetManualRating(player string) (int, bool) {
+	value, err := db.GetDBValue(getRatingTag(player))
+	if err != nil {
+		return 0, false
+	}
+	rating, err := strconv.Atoi(value)
+	return rating, err == nil
+}
+
+// teamPlayers splits a score team name such as <@U1>+<@U2> into its players
+func teamPlayers(team string) []string {
+	var players []string
+	for _, p := range strings.FieldsFunc(team, func(r rune) bool { return r == '+' || r == ',' }) {
+		if p = strings.TrimSpace(p); p != "" {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+type ratedMatch struct {
+	team1  []string
+	team2  []string
+	result float64
+	time   time.Time
+}
+
+// matchRatings computes Elo ratings of all players from the recorded matches in the order they were played.
+// Team names made of several players joined with + (e.g. <@U1>+<@U2>:<@U3>+<@U4>) rate every player in them
+func (db *DB) matchRatings() (map[string]float64, error) {
+	var matches []ratedMatch
+
+	err := db.IterateDBPrefix("[SCORE]", func(key string, value string) bool {
+		scoreInfo, err := jsonToScoreInfo(value)
+		if err != nil {
+			return true
+		}
+
+		team1, team2 := teamPlayers(scoreInfo.Team1), teamPlayers(scoreInfo.Team2)
+		for _, s := range scoreInfo.Scores {
+			t, _ := time.Parse(time.RFC1123, s.Timestamp)
+			result := 0.5
+			if s.Team1 > s.Team2 {
+				result = 1
+			} else if s.Team1 < s.Team2 {
+				result = 0
+			}
+			matches = append(matches, ratedMatch{team1: team1, team2: team2, result: result, time: t})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].time.Before(matches[j].time) })
+
+	ratings := make(map[string]float64)
+	rating := func(p string) float64 {
+		if r, ok := ratings[p]; ok {
+			return r
+		}
+		return DefaultRating
+	}
+	average := func(team []string) float64 {
+		sum := 0.0
+		for _, p := range team {
+			sum += rating(p)
+		}
+		return sum / float64(len(team))
+	}
+
+	for _, m := range matches {
+		if len(m.team1) == 0 || len(m.team2) == 0 {
+			continue
+		}
+
+		expected := 1 / (1 + math.Pow(10, (average(m.team2)-average(m.team1))/400))
+		delta := EloK * (m.result - expected)
+
+		for _, p := range m.team1 {
+			ratings[p] = rating(p) + delta
+		}
+		for _, p := range m.team2 {
+			ratings[p] = rating(p) - delta
+		}
+	}
+
+	return ratings, nil
+}
+
+// GetRatings returns the rating of every member: the manual rating if set, otherwise the rating from recorded matches,
+// otherwise DefaultRating
+func (db *DB) GetRatings(members []string) (map[string]float64, error) {
+	computed, err := db.matchRatings()
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make(map[string]float64)
+	for _, m := range members {
+		if r, ok := db.GetManualRating(m); ok {
+			ratings[m] = float64(r)
+		} else if r, ok := computed[strings.ToUpper(m)]; ok {
+			ratings[m] = r
+		} else {
+			ratings[m] = DefaultRating
+		}
+	}
+
+	return ratings, nil
+}
+
+// TeamStrength returns the expected strength of team, the average rating of its members
+func TeamStrength(team []string, ratings map[string]float64) float64 {
+	if len(team) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, m := range team {
+		r, ok := ratings[m]
+		if !ok {
+			r = DefaultRating
+		}
+		sum += r
+	}
+	return sum / float64(len(team))
+}
+
+// strengthSpread returns the difference between the strongest and the weakest team
+func strengthSpread(teams [][]string, ratings map[string]float64) float64 {
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, team := range teams {
+		s := TeamStrength(team, ratings)
+		lowest = math.Min(lowest, s)
+		highest = math.Max(highest, s)
+	}
+	return highest - lowest
+}
+
+// improveBalance swaps members between teams as long as that makes the teams more even
+func improveBalance(teams [][]string, ratings map[string]float64) {
+	spread := strengthSpread(teams, ratings)
+
+	for improved := true; improved; {
+		improved = false
+		for a := 0; a < len(teams); a++ {
+			for b := a + 1; b < len(teams); b++ {
+				for i := range teams[a] {
+					for j := range teams[b] {
+						teams[a][i], teams[b][j] = teams[b][j], teams[a][i]
+						if s := strengthSpread(teams, ratings); s < spread-1e-9 {
+							spread = s
+							improved = true
+						} else {
+							teams[a][i], teams[b][j] = teams[b][j], teams[a][i]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// DrawBalancedTeams splits members into teams of teamSize with strengths as even as possible. It improves many random splits,
+// then picks one at random among those within BalanceTolerance of the best, so the same seed and ratings give the same teams
+func DrawBalancedTeams(teamSize int, members []string, ratings map[string]float64, seed string) ([][]string, error) {
+	if teamSize < 1 {
+		return nil, errors.New("Team size must be at least 1")
+	}
+
+	if teamSize > len(members)/2 {
+		return nil, errors.New("Team size can't be more than half the group size")
+	}
+
+	rng, err := newSeededRand(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates [][][]string
+	var spreads []float64
+	best := math.Inf(1)
+
+	for i := 0; i < balancedCandidates; i++ {
+		teams := drawTeamList(teamSize, members, false, rng)
+		improveBalance(teams, ratings)
+
+		spread := strengthSpread(teams, ratings)
+		candidates = append(candidates, teams)
+		spreads = append(spreads, spread)
+		best = math.Min(best, spread)
+	}
+
+	var near [][][]string
+	for i, teams := range candidates {
+		if spreads[i] <= best+BalanceTolerance {
+			near = append(near, teams)
+		}
+	}
+
+	return near[rng.intn(len(near))], nil
+}
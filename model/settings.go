@@ -0,0 +1,148 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Channel setting names used by SetChannelSetting
+const (
+	SettingGroup      = "group"
+	SettingTeamSize   = "teamsize"
+	SettingMatchup    = "matchup"
+	SettingAnimations = "animations"
+)
+
+// SettingNames lists the channel settings in the order they are shown
+var SettingNames = []string{SettingGroup, SettingTeamSize, SettingMatchup, SettingAnimations}
+
+// ChannelSettings are the defaults of a channel. Zero values mean the setting isn't set
+type ChannelSettings struct {
+	// DefaultGroup is drawn from by randompairs and randomteams without members
+	DefaultGroup string `json:",omitempty"`
+	// TeamSize is used by randomteams without a team size
+	TeamSize int `json:",omitempty"`
+	// ScoreMatchup (TEAM1:TEAM2) is used by score commands without teams
+	ScoreMatchup string `json:",omitempty"`
+	// AnimationsDisabled turns off shark and animate
+	AnimationsDisabled bool `json:",omitempty"`
+}
+
+func getChannelSettingsTag(channel string) string {
+	return "[settings::channel::" + channel + "]"
+}
+
+// GetChannelSettings returns the settings of channel, which are all unset if none were stored
+func (db *DB) GetChannelSettings(channel string) ChannelSettings {
+	var settings ChannelSettings
+
+	value, err := db.GetDBValue(getChannelSettingsTag(channel))
+	if err == nil {
+		_ = json.Unmarshal([]byte(value), &settings)
+	}
+
+	return settings
+}
+
+// Get returns the value of setting name as text, or "" if it isn't set
+func (settings ChannelSettings) Get(name string) string {
+	switch name {
+	case SettingGroup:
+		return settings.DefaultGroup
+	case SettingTeamSize:
+		if settings.TeamSize > 0 {
+			return strconv.Itoa(settings.TeamSize)
+		}
+	case SettingMatchup:
+		return settings.ScoreMatchup
+	case SettingAnimations:
+		if settings.AnimationsDisabled {
+			return "off"
+		}
+		return "on"
+	}
+	return ""
+}
+
+// SetChannelSetting validates and stores setting name of channel. An empty value unsets it
+func (db *DB) SetChannelSetting(channel string, name string, value string) error {
+	tag := getChannelSettingsTag(channel)
+	defer db.lockKey(tag)()
+
+	settings := db.GetChannelSettings(channel)
+
+	switch name {
+	case SettingGroup:
+		if value != "" {
+			if _, err := db.GetGroupInfo(value); err != nil {
+				return errors.New("group " + value + " doesn't exist")
+			}
+		}
+		settings.DefaultGroup = value
+	case SettingTeamSize:
+		n := 0
+		if value != "" {
+			var err error
+			n, err = strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return errors.New("team size must be a number of at least 1")
+			}
+		}
+		settings.TeamSize = n
+	case SettingMatchup:
+		value = strings.ToUpper(value)
+		if value != "" {
+			teams := strings.Split(value, ":")
+			if len(teams) != 2 || teams[0] == "" || teams[1] == "" {
+				return errors.New("matchup must look like TEAM1:TEAM2")
+			}
+		}
+		settings.ScoreMatchup = value
+	case SettingAnimations:
+		switch strings.ToLower(value) {
+		case "", "on", "true", "yes":
+			settings.AnimationsDisabled = false
+		case "off", "false", "no":
+			settings.AnimationsDisabled = true
+		default:
+			return errors.New("animations must be on or off")
+		}
+	default:
+		return errors.New("unknown setting " + name)
+	}
+
+	if settings == (ChannelSettings{}) {
+		return db.DeleteDBValue(tag)
+	}
+
+	js, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return db.SetDBValue(tag, string(js))
+}
@@ -0,0 +1,75 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"errors"
+)
+
+// ErrNotFound is returned by stores when a key doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// Store is the key-value storage behind the model package
+type Store interface {
+	// Get returns the value at key, or ErrNotFound
+	Get(key string) (string, error)
+	// Put sets the value at key
+	Put(key string, value string) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(key string) error
+	// Iterate calls fn for every key starting with prefix, in key order, until fn returns false.
+	// fn sees a consistent view and may modify the store
+	Iterate(prefix string, fn func(key string, value string) bool) error
+	// Write applies all operations in batch atomically
+	Write(batch *Batch) error
+	// Close releases the store
+	Close() error
+}
+
+// BatchOp is a single put or delete in a Batch
+type BatchOp struct {
+	Delete bool
+	Key    string
+	Value  string
+}
+
+// Batch collects puts and deletes that are written together
+type Batch struct {
+	Ops []BatchOp
+}
+
+// Put adds a put operation to the batch
+func (b *Batch) Put(key string, value string) {
+	b.Ops = append(b.Ops, BatchOp{Key: key, Value: value})
+}
+
+// Delete adds a delete operation to the batch
+func (b *Batch) Delete(key string) {
+	b.Ops = append(b.Ops, BatchOp{Delete: true, Key: key})
+}
+
+// Len returns the number of operations in the batch
+func (b *Batch) Len() int {
+	return len(b.Ops)
+}
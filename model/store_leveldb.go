@@ -0,0 +1,97 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// LevelDBStore is a Store backed by a LevelDB directory
+type LevelDBStore struct {
+	db *leveldb.DB
+}
+
+// NewLevelDBStore opens (or creates) the LevelDB at path
+func NewLevelDBStore(path string) (*LevelDBStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBStore{db: db}, nil
+}
+
+// Get returns the value at key
+func (s *LevelDBStore) Get(key string) (string, error) {
+	data, err := s.db.Get([]byte(key), nil)
+	if err == leveldb.ErrNotFound {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Put sets the value at key
+func (s *LevelDBStore) Put(key string, value string) error {
+	return s.db.Put([]byte(key), []byte(value), nil)
+}
+
+// Delete removes key
+func (s *LevelDBStore) Delete(key string) error {
+	return s.db.Delete([]byte(key), nil)
+}
+
+// Iterate calls fn for every key starting with prefix. LevelDB iterators work on an implicit snapshot
+func (s *LevelDBStore) Iterate(prefix string, fn func(key string, value string) bool) error {
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if !fn(string(iter.Key()), string(iter.Value())) {
+			break
+		}
+	}
+
+	return iter.Error()
+}
+
+// Write applies batch atomically
+func (s *LevelDBStore) Write(batch *Batch) error {
+	b := new(leveldb.Batch)
+	for _, op := range batch.Ops {
+		if op.Delete {
+			b.Delete([]byte(op.Key))
+		} else {
+			b.Put([]byte(op.Key), []byte(op.Value))
+		}
+	}
+	return s.db.Write(b, nil)
+}
+
+// Close closes the LevelDB
+func (s *LevelDBStore) Close() error {
+	return s.db.Close()
+}
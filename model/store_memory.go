@@ -0,0 +1,115 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package model contains db access and data manipulation functions
+package model
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps everything in memory, used in tests
+type MemoryStore struct {
+	mutex sync.RWMutex
+	data  map[string]string
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string]string)}
+}
+
+// Get returns the value at key
+func (s *MemoryStore) Get(key string) (string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	value, ok := s.data[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Put sets the value at key
+func (s *MemoryStore) Put(key string, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data[key] = value
+	return nil
+}
+
+// Delete removes key
+func (s *MemoryStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data, key)
+	return nil
+}
+
+// Iterate calls fn for every key starting with prefix on a copy of the matching pairs
+func (s *MemoryStore) Iterate(prefix string, fn func(key string, value string) bool) error {
+	s.mutex.RLock()
+	var keys []string
+	snapshot := make(map[string]string)
+	for key, value := range s.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+			snapshot[key] = value
+		}
+	}
+	s.mutex.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !fn(key, snapshot[key]) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Write applies batch atomically
+func (s *MemoryStore) Write(batch *Batch) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, op := range batch.Ops {
+		if op.Delete {
+			delete(s.data, op.Key)
+		} else {
+			s.data[op.Key] = op.Value
+		}
+	}
+	return nil
+}
+
+// Close does nothing for a MemoryStore
+func (s *MemoryStore) Close() error {
+	return nil
+}
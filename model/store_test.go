@@ -0,0 +1,115 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testStore checks the behavior every Store implementation must share
+func testStore(t *testing.T, s Store) {
+	if _, err := s.Get("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	s.Put("[a]2", "two")
+	s.Put("[a]1", "one")
+	s.Put("[b]1", "other")
+
+	if err := s.Delete("missing"); err != nil {
+		t.Errorf("Deleting a missing key failed: %v", err)
+	}
+
+	var keys []string
+	err := s.Iterate("[a]", func(key string, value string) bool {
+		keys = append(keys, key)
+		// modifying the store while iterating must not affect the iteration
+		s.Put("[a]3", "three")
+		return true
+	})
+	if err != nil || len(keys) != 2 || keys[0] != "[a]1" || keys[1] != "[a]2" {
+		t.Errorf("Unexpected iteration %v (%v)", keys, err)
+	}
+
+	batch := new(Batch)
+	batch.Delete("[a]1")
+	batch.Put("[a]1", "uno")
+	batch.Delete("[b]1")
+	if err = s.Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := s.Get("[a]1"); value != "uno" {
+		t.Errorf("Batch operations were applied out of order: %q", value)
+	}
+	if _, err = s.Get("[b]1"); err != ErrNotFound {
+		t.Error("Batch delete wasn't applied")
+	}
+}
+
+// TestMemoryStore tests the in-memory store
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	defer s.Close()
+	testStore(t, s)
+}
+
+// TestLevelDBStore tests the LevelDB store
+func TestLevelDBStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hinko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewLevelDBStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+}
+
+// TestBoltStore tests the bbolt store
+func TestBoltStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hinko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewBoltStore(filepath.Join(dir, "hinko.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+
+	dst := NewMemoryStore()
+	if count, err := CopyStore(s, dst); err != nil || count != 3 {
+		t.Errorf("Expected to copy 3 keys, copied %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,336 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+// Package plugins discovers and runs external command plugins that talk to hinko over a JSON stdin/stdout protocol
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tadej/hinko/slack"
+)
+
+// DefaultTimeout is used when a plugin manifest doesn't specify one
+var DefaultTimeout = 5 * time.Second
+
+// MaxOutputSize limits how many bytes a plugin may write to stdout
+var MaxOutputSize = 1 << 20
+
+// processWaitDelay is how long Run waits for the plugin's output to be closed after it exits or is killed
+var processWaitDelay = time.Second
+
+// rescanInterval is the minimum time between two scans of the plugin directory
+var rescanInterval = 2 * time.Second
+
+// Manifest describes a plugin; it is read from a .json file in the plugin directory
+type Manifest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Executable  string   `json:"executable"`
+	Commands    []string `json:"commands"`
+	Timeout     string   `json:"timeout"`
+	KVKeys      []string `json:"kv_keys"`
+}
+
+// Plugin is a loaded plugin with its resolved executable path
+type Plugin struct {
+	Manifest
+	Path    string
+	timeout time.Duration
+	modTime time.Time
+}
+
+// Message holds the MessageInfo fields that are passed to a plugin
+type Message struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Channel   string `json:"channel"`
+	IM        bool   `json:"im"`
+	Text      string `json:"text"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Request is written as JSON to the plugin's stdin
+type Request struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Message Message           `json:"message"`
+	KV      map[string]string `json:"kv"`
+}
+
+// Response is read as JSON from the plugin's stdout. Only the KV writes to keys listed in the manifest's KVKeys are honored
+type Response struct {
+	Text      string            `json:"text"`
+	Reactions []string          `json:"reactions"`
+	KV        map[string]string `json:"kv"`
+}
+
+var (
+	mutex    sync.Mutex
+	dir      string
+	loaded   map[string]*Plugin // manifest path -> plugin
+	commands map[string]*Plugin // command name -> plugin
+	lastScan time.Time
+)
+
+// Init sets the plugin directory and performs the initial scan
+func Init(path string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	dir = path
+	loaded = make(map[string]*Plugin)
+	commands = make(map[string]*Plugin)
+	scan()
+}
+
+// scan (re)loads manifests whose files changed since the last scan. mutex must be held
+func scan() {
+	lastScan = time.Now()
+	if dir == "" {
+		return
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		manifestPath := filepath.Join(dir, f.Name())
+		seen[manifestPath] = true
+
+		if p, ok := loaded[manifestPath]; ok && p.modTime.Equal(f.ModTime()) {
+			continue
+		}
+
+		p, err := loadManifest(manifestPath)
+		if err != nil {
+			fmt.Printf("Skipping plugin %s: %s\n", manifestPath, err)
+			delete(loaded, manifestPath)
+			continue
+		}
+		p.modTime = f.ModTime()
+		loaded[manifestPath] = p
+	}
+
+	for manifestPath := range loaded {
+		if !seen[manifestPath] {
+			delete(loaded, manifestPath)
+		}
+	}
+
+	commands = make(map[string]*Plugin)
+	for _, p := range loaded {
+		for _, c := range p.Commands {
+			commands[strings.ToLower(c)] = p
+		}
+	}
+}
+
+func loadManifest(path string) (*Plugin, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Plugin
+	if err = json.Unmarshal(data, &p.Manifest); err != nil {
+		return nil, err
+	}
+
+	if p.Executable == "" || len(p.Commands) == 0 {
+		return nil, errors.New("manifest needs an executable and at least one command")
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+
+	p.Path = p.Executable
+	if !filepath.IsAbs(p.Path) {
+		p.Path = filepath.Join(filepath.Dir(path), p.Path)
+	}
+
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return nil, errors.New(p.Path + " is not executable")
+	}
+
+	p.timeout = DefaultTimeout
+	if p.Timeout != "" {
+		p.timeout, err = time.ParseDuration(p.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+// Find returns the plugin that handles command, rescanning the plugin directory if needed
+func Find(command string) *Plugin {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if time.Since(lastScan) > rescanInterval {
+		scan()
+	}
+
+	return commands[strings.ToLower(command)]
+}
+
+// List returns all loaded plugins sorted by name
+func List() []*Plugin {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	scan()
+
+	var ret []*Plugin
+	for _, p := range loaded {
+		ret = append(ret, p)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+
+	return ret
+}
+
+// NewRequest builds a plugin request for the command in parts. kv holds the values of the manifest's KVKeys
+func NewRequest(parts []string, msg slack.MessageInfo, kv map[string]string) Request {
+	return Request{
+		Command: strings.ToLower(parts[0]),
+		Args:    parts[1:],
+		Message: Message{UserID: msg.UserID, Username: msg.Username, Channel: msg.Channel,
+			IM: msg.IM, Text: msg.Message, Timestamp: msg.Timestamp},
+		KV: kv,
+	}
+}
+
+// limitedBuffer stops accepting data once limit bytes have been written
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		b.overflow = true
+		return 0, errors.New("plugin output too large")
+	}
+	return b.Buffer.Write(p)
+}
+
+// Run executes the plugin with req on stdin and parses its response. The plugin and any processes it started are killed
+// when its timeout expires or it exits
+func (p *Plugin) Run(req Request) (resp Response, err error) {
+	// a misbehaving plugin must never take the bot down with it
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin %s: %v", p.Name, r)
+		}
+	}()
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: MaxOutputSize}
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Dir = filepath.Dir(p.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	// processes the plugin starts are killed with it, and a leftover one that keeps stdout open can't block Wait
+	startProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = processWaitDelay
+
+	err = cmd.Run()
+	if cmd.Process != nil {
+		_ = killProcessGroup(cmd)
+	}
+	// the plugin exited fine, only a process it left behind still had its output open
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return resp, fmt.Errorf("plugin %s timed out after %s", p.Name, p.timeout)
+	}
+	if stdout.overflow {
+		return resp, fmt.Errorf("plugin %s wrote more than %d bytes", p.Name, MaxOutputSize)
+	}
+	if err != nil {
+		return resp, fmt.Errorf("plugin %s failed: %s %s", p.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	err = json.Unmarshal(stdout.Bytes(), &resp)
+	if err != nil {
+		return resp, fmt.Errorf("plugin %s returned invalid JSON: %s", p.Name, err)
+	}
+
+	// a plugin may only write the keys its manifest lists
+	for key := range resp.KV {
+		if !p.hasKVKey(key) {
+			fmt.Printf("Plugin %s can't write key %s\n", p.Name, key)
+			delete(resp.KV, key)
+		}
+	}
+
+	return resp, nil
+}
+
+// hasKVKey reports whether key is listed in the plugin's KVKeys
+func (p *Plugin) hasKVKey(key string) bool {
+	for _, k := range p.KVKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+//MIT License
+
+//Copyright(c) 2019 Tadej Gregorcic
+
+//Permission is hereby granted, free of charge, to any person obtaining a copy
+//of this software and associated documentation files (the "Software"), to deal
+//in the Software without restriction, including without limitation the rights
+//to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+//copies of the Software, and to permit persons to whom the Software is
+//furnished to do so, subject to the following conditions:
+
+//The above copyright notice and this permission notice shall be included in all
+//copies or substantial portions of the Software.
+
+//THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+//IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+//FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+//AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+//LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+//OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+//SOFTWARE.
+
+package plugins
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestPlugin writes script and a manifest for it to a temporary directory and loads the plugin
+func writeTestPlugin(t *testing.T, script string, timeout string) *Plugin {
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.sh"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"executable": "test.sh", "commands": ["test"], "timeout": "` + timeout + `"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadManifest(filepath.Join(dir, "test.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// TestRunKillsBackgroundProcesses tests that a plugin that leaves a process behind times out or returns promptly
+func TestRunKillsBackgroundProcesses(t *testing.T) {
+	t.Parallel()
+
+	p := writeTestPlugin(t, "sleep 30 &\nsleep 30\n", "200ms")
+	start := time.Now()
+	_, err := p.Run(Request{Command: "test"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run waited %s for the background process", elapsed)
+	}
+
+	p = writeTestPlugin(t, "sleep 30 &\necho '{\"text\": \"hi\"}'\n", "10s")
+	start = time.Now()
+	resp, err := p.Run(Request{Command: "test"})
+	if err != nil || resp.Text != "hi" {
+		t.Errorf("Unexpected response %v (%v)", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run waited %s for the background process", elapsed)
+	}
+}
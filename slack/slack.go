@@ -28,6 +28,7 @@ package slack
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/nlopes/slack"
 )
@@ -48,8 +49,16 @@ type MessageInfo struct {
 	Timestamp string
 }
 
+// SubteamChangedHandler is called with the ID of a Slack user group whenever it or its members change
+var SubteamChangedHandler func(subteamID string)
+
 // Init initializes the Slack connection
 func Init(token string) {
+	// the RTM client doesn't map this event by default
+	if _, ok := slack.EventMapping["subteam_members_changed"]; !ok {
+		slack.EventMapping["subteam_members_changed"] = slack.SubteamMembersChangedEvent{}
+	}
+
 	api = slack.New(token)
 	rtm = api.NewRTM()
 	go rtm.ManageConnection()
@@ -81,6 +90,16 @@ Loop:
 					c <- ret
 				}
 
+			case *slack.SubteamUpdatedEvent:
+				if SubteamChangedHandler != nil {
+					go SubteamChangedHandler(ev.Subteam.ID)
+				}
+
+			case *slack.SubteamMembersChangedEvent:
+				if SubteamChangedHandler != nil {
+					go SubteamChangedHandler(ev.SubteamID)
+				}
+
 			case *slack.RTMError:
 				fmt.Printf("Error: %s\n\n", ev.Error())
 
@@ -112,6 +131,47 @@ func UpdateMessage(channel string, timestamp string, text string) error {
 	return err
 }
 
+// UploadFile uploads content as a file into the selected Slack channel
+func UploadFile(channel string, filename string, content string) error {
+	_, err := api.UploadFile(slack.FileUploadParameters{Content: content, Filename: filename,
+		Title: filename, Channels: []string{channel}})
+	return err
+}
+
+// OpenDirectMessage returns the ID of the direct message channel with a user, opening it if needed
+func OpenDirectMessage(userID string) (string, error) {
+	_, _, channel, err := api.OpenIMChannel(userID)
+	return channel, err
+}
+
+// GetUserStatusEmojis returns the status emoji, e.g. :palm_tree:, of every active user in the workspace by user ID.
+// Users without a status have an empty one
+func GetUserStatusEmojis() (map[string]string, error) {
+	users, err := api.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string)
+	for _, u := range users {
+		if !u.Deleted {
+			statuses[u.ID] = u.Profile.StatusEmoji
+		}
+	}
+	return statuses, nil
+}
+
+// GetUserGroupMembers returns the IDs of the members of a Slack user group
+func GetUserGroupMembers(userGroup string) ([]string, error) {
+	return api.GetUserGroupMembers(userGroup)
+}
+
+// SetUserGroupMembers replaces the members of a Slack user group
+func SetUserGroupMembers(userGroup string, members []string) error {
+	_, err := api.UpdateUserGroupMembers(userGroup, strings.Join(members, ","))
+	return err
+}
+
 // AddReaction adds the specified reaction to a message defined by channel and timestamp
 func AddReaction(author string, channel string, timestamp string, reaction string) {
 	if author == "slackbot" {